@@ -0,0 +1,180 @@
+package gogp2
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+
+	Log "github.com/qazf88/golog"
+)
+
+// Histogram of an image, 8 bits per channel
+type Histogram struct {
+	Luma   [256]uint64 `json:"luma"`
+	Red    [256]uint64 `json:"red"`
+	Green  [256]uint64 `json:"green"`
+	Blue   [256]uint64 `json:"blue"`
+	Pixels uint64      `json:"pixels"`
+
+	ShadowPixels    uint64 `json:"shadowPixels"`    // pixels black in all channels
+	HighlightPixels uint64 `json:"highlightPixels"` // pixels white in at least one channel
+}
+
+// FrameStats
+type FrameStats struct {
+	Histogram  *Histogram `json:"histogram"`
+	Brightness float64    `json:"brightness"` // mean luma, 0..255
+	Shadows    float64    `json:"shadows"`    // percentage of clipped shadows
+	Highlights float64    `json:"highlights"` // percentage of clipped highlights
+	Sharpness  float64    `json:"sharpness"`  // variance of the Laplacian, greater is sharper
+}
+
+// DecodeFrame decodes a JPEG preview frame
+func DecodeFrame(data []byte) (image.Image, error) {
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		err := fmt.Sprintf("cannot decode frame: %s", err.Error())
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	return img, nil
+}
+
+// CapturePreviewImage
+func (c *Camera) CapturePreviewImage() (image.Image, error) {
+
+	var buffer bytes.Buffer
+	err := c.CapturePreview(&buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeFrame(buffer.Bytes())
+}
+
+// AnalyzeFrame decodes a frame and measures its exposure and sharpness
+func AnalyzeFrame(data []byte) (*FrameStats, error) {
+
+	img, err := DecodeFrame(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Analyze(img), nil
+}
+
+// Analyze measures exposure and sharpness of img, use SubImage to restrict it to a region
+func Analyze(img image.Image) *FrameStats {
+
+	h := NewHistogram(img)
+
+	return &FrameStats{
+		Histogram:  h,
+		Brightness: h.Mean(),
+		Shadows:    h.ShadowClipping(),
+		Highlights: h.HighlightClipping(),
+		Sharpness:  Sharpness(img),
+	}
+}
+
+// NewHistogram
+func NewHistogram(img image.Image) *Histogram {
+
+	h := &Histogram{}
+	b := img.Bounds()
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			r, g, bl = r>>8, g>>8, bl>>8
+
+			h.Red[r]++
+			h.Green[g]++
+			h.Blue[bl]++
+			h.Luma[luma(r, g, bl)]++
+
+			if r == 0 && g == 0 && bl == 0 {
+				h.ShadowPixels++
+			}
+			if r == 255 || g == 255 || bl == 255 {
+				h.HighlightPixels++
+			}
+		}
+	}
+	h.Pixels = uint64(b.Dx() * b.Dy())
+
+	return h
+}
+
+// Mean luma, 0..255
+func (h *Histogram) Mean() float64 {
+
+	if h.Pixels == 0 {
+		return 0
+	}
+
+	var sum uint64
+	for value, count := range h.Luma {
+		sum += uint64(value) * count
+	}
+
+	return float64(sum) / float64(h.Pixels)
+}
+
+// ShadowClipping percentage of pixels black in all channels
+func (h *Histogram) ShadowClipping() float64 {
+	return percent(h.ShadowPixels, h.Pixels)
+}
+
+// HighlightClipping percentage of pixels white in at least one channel
+func (h *Histogram) HighlightClipping() float64 {
+	return percent(h.HighlightPixels, h.Pixels)
+}
+
+// Sharpness is the variance of the Laplacian of the luma, greater is sharper
+func Sharpness(img image.Image) float64 {
+
+	b := img.Bounds()
+	w, ht := b.Dx(), b.Dy()
+	if w < 3 || ht < 3 {
+		return 0
+	}
+
+	gray := make([]float64, w*ht)
+	for y := 0; y < ht; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			gray[y*w+x] = float64(luma(r>>8, g>>8, bl>>8))
+		}
+	}
+
+	var sum, sumSq float64
+	n := float64((w - 2) * (ht - 2))
+	for y := 1; y < ht-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			l := gray[i-w] + gray[i+w] + gray[i-1] + gray[i+1] - 4*gray[i]
+			sum += l
+			sumSq += l * l
+		}
+	}
+
+	mean := sum / n
+	return sumSq/n - mean*mean
+}
+
+// luma Rec. 601 of 8 bit channels
+func luma(r, g, b uint32) uint32 {
+	return (299*r + 587*g + 114*b + 500) / 1000
+}
+
+// percent
+func percent(part, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
@@ -0,0 +1,165 @@
+package gogp2
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// gamma of the preview JPEG, converts a luma ratio to stops of light
+const previewGamma = 2.2
+
+// time the live view takes to show a new exposure
+const exposureSettleTime = 300 * time.Millisecond
+
+// AutoExposureOptions
+type AutoExposureOptions struct {
+	Target        float64             // mean luma to reach, 118 by default
+	Tolerance     float64             // accepted distance from Target, 8 by default
+	Region        image.Rectangle     // region of the preview frame in pixels, empty measures the whole frame
+	Parameters    []ExposureParameter // parameters adjusted in order, shutter speed then ISO by default
+	MaxIterations int                 // 10 by default
+}
+
+// AutoExposureResult
+type AutoExposureResult struct {
+	Brightness float64           `json:"brightness"` // last measured mean luma
+	Values     map[string]string `json:"values"`     // widget values in use, by widget name
+	Iterations int               `json:"iterations"`
+}
+
+// AutoExposure adjusts the exposure until the preview brightness is within tolerance of the target
+func (c *Camera) AutoExposure(ctx context.Context, options AutoExposureOptions) (*AutoExposureResult, error) {
+
+	if options.Target <= 0 {
+		options.Target = 118
+	}
+	if options.Tolerance <= 0 {
+		options.Tolerance = 8
+	}
+	if len(options.Parameters) == 0 {
+		options.Parameters = []ExposureParameter{ExposureShutter, ExposureISO}
+	}
+	if options.MaxIterations <= 0 {
+		options.MaxIterations = 10
+	}
+
+	result := &AutoExposureResult{Values: map[string]string{}}
+
+	for result.Iterations < options.MaxIterations {
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		img, err := c.CapturePreviewImage()
+		if err != nil {
+			return result, err
+		}
+		result.Brightness = measureBrightness(img, options.Region)
+		result.Iterations++
+
+		if math.Abs(result.Brightness-options.Target) <= options.Tolerance {
+			return result, nil
+		}
+
+		stops := brightnessStops(result.Brightness, options.Target)
+		applied, err := c.shiftExposure(options.Parameters, stops, result.Values, 0)
+		if err != nil {
+			return result, err
+		}
+		if applied == 0 && math.Abs(stops) < 0.5 {
+			// closer than the steps of the choice lists allow
+			return result, nil
+		}
+		if applied == 0 {
+			err := fmt.Sprintf("cannot reach brightness %.0f, measured %.0f, exposure out of range", options.Target, result.Brightness)
+			Log.Error(err)
+			return result, fmt.Errorf(err)
+		}
+
+		time.Sleep(exposureSettleTime)
+	}
+
+	err := fmt.Sprintf("brightness %.0f not reached after %d iterations, measured %.0f", options.Target, result.Iterations, result.Brightness)
+	Log.Error(err)
+	return result, fmt.Errorf(err)
+}
+
+// measureBrightness returns the mean luma of region, the whole image if region is empty
+func measureBrightness(img image.Image, region image.Rectangle) float64 {
+
+	if !region.Empty() {
+		if sub, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}); ok {
+			img = sub.SubImage(region.Intersect(img.Bounds()))
+		}
+	}
+
+	return NewHistogram(img).Mean()
+}
+
+// brightnessStops converts the distance between two luma values to stops of light
+func brightnessStops(measured, target float64) float64 {
+	return previewGamma * math.Log2(target/math.Max(measured, 1))
+}
+
+// shiftExposure changes the exposure by stops, using params in order, and records new values in values
+// returns the stops actually applied, shutter speeds longer than maxShutter are not used unless it is 0
+func (c *Camera) shiftExposure(params []ExposureParameter, stops float64, values map[string]string, maxShutter time.Duration) (float64, error) {
+
+	applied := 0.0
+
+	for _, param := range params {
+
+		if math.Abs(stops-applied) < 1.0/6 {
+			break
+		}
+
+		wName, err := c.exposureWidgetName(param)
+		if err != nil {
+			continue
+		}
+
+		current, err := c.GetWidgetValueByName(wName)
+		if err != nil {
+			return applied, err
+		}
+
+		ev, ok := exposureValue(param, current)
+		if !ok {
+			// "Auto", "bulb" and the like are left alone
+			continue
+		}
+
+		choices, err := c.GetWidgetChoicesByName(wName)
+		if err != nil {
+			return applied, err
+		}
+		if param == ExposureShutter && maxShutter > 0 {
+			choices = shutterChoicesUpTo(choices, maxShutter)
+		}
+
+		value, newEV, ok := nearestChoice(param, choices, ev+stops-applied)
+		if !ok || value == current {
+			continue
+		}
+
+		err = c.SetWigetValueByName(wName, value)
+		if err != nil {
+			return applied, err
+		}
+
+		applied += newEV - ev
+		if values != nil {
+			values[wName] = value
+		}
+	}
+
+	return applied, nil
+}
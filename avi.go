@@ -0,0 +1,289 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"math"
+
+	Log "github.com/qazf88/golog"
+)
+
+// offsets of the fields patched when the AVI file is closed
+const (
+	aviRiffSize        = 4
+	aviTotalFrames     = 48
+	aviMainBufferSize  = 60
+	aviStreamLength    = 140
+	aviStreamBuffer    = 144
+	aviMoviSize        = 216
+	aviMoviStart       = 220
+	aviKeyFrame        = 0x10
+	aviHasIndex        = 0x10
+	aviFrameRateFactor = 1000
+	aviMaxSize         = 1 << 30 // sizes and offsets are 32 bit, many players fail on larger files already
+)
+
+// ErrAVITooLarge is returned by WriteFrame when the frame would make the file larger than an AVI 1.0 file can be,
+// the file can still be closed and holds every frame written before
+var ErrAVITooLarge = errors.New("AVI file size limit reached")
+
+// AVIWriter writes JPEG frames as MJPEG in an AVI container
+type AVIWriter struct {
+	w       io.WriteSeeker
+	fps     float64
+	start   int64
+	offset  int64 // bytes written after the start
+	frames  int
+	width   int
+	height  int
+	maxLen  int
+	index   bytes.Buffer
+	maxSize int64
+}
+
+// NewAVIWriter the size of the video is taken from the first frame
+func NewAVIWriter(w io.WriteSeeker, fps float64) (*AVIWriter, error) {
+
+	if fps <= 0 {
+		err := fmt.Sprintf("invalid frame rate: %f", fps)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	return &AVIWriter{w: w, fps: fps, start: start, maxSize: aviMaxSize}, nil
+}
+
+// WriteFrame appends a JPEG frame
+func (a *AVIWriter) WriteFrame(frame []byte) error {
+
+	if a.frames == 0 {
+		config, err := jpeg.DecodeConfig(bytes.NewReader(frame))
+		if err != nil {
+			err := fmt.Sprintf("frame is not a JPEG image: %s", err.Error())
+			Log.Error(err)
+			return fmt.Errorf(err)
+		}
+		a.width, a.height = config.Width, config.Height
+
+		err = a.write(a.header())
+		if err != nil {
+			return err
+		}
+	}
+
+	// the frame, its index entry and the idx1 header written by Close have to fit
+	size := a.offset + 8 + int64(len(frame)+len(frame)%2) + int64(a.index.Len()) + 16 + 8
+	if size > a.maxSize {
+		Log.Error(ErrAVITooLarge.Error())
+		return ErrAVITooLarge
+	}
+
+	chunk := bytes.Buffer{}
+	chunk.WriteString("00dc")
+	binary.Write(&chunk, binary.LittleEndian, uint32(len(frame)))
+	chunk.Write(frame)
+	if len(frame)%2 == 1 {
+		chunk.WriteByte(0)
+	}
+
+	a.index.WriteString("00dc")
+	binary.Write(&a.index, binary.LittleEndian, []uint32{aviKeyFrame, uint32(a.offset - aviMoviStart), uint32(len(frame))})
+
+	err := a.write(chunk.Bytes())
+	if err != nil {
+		return err
+	}
+
+	a.frames++
+	if len(frame) > a.maxLen {
+		a.maxLen = len(frame)
+	}
+
+	return nil
+}
+
+// Close writes the index and completes the headers, the underlying writer is left open
+func (a *AVIWriter) Close() error {
+
+	if a.frames == 0 {
+		err := "cannot write AVI file without frames"
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	moviEnd := a.offset
+
+	idx := bytes.Buffer{}
+	idx.WriteString("idx1")
+	binary.Write(&idx, binary.LittleEndian, uint32(a.index.Len()))
+	idx.Write(a.index.Bytes())
+	err := a.write(idx.Bytes())
+	if err != nil {
+		return err
+	}
+
+	patches := []struct {
+		offset int64
+		value  uint32
+	}{
+		{aviRiffSize, uint32(a.offset - 8)},
+		{aviTotalFrames, uint32(a.frames)},
+		{aviMainBufferSize, uint32(a.maxLen + 8)},
+		{aviStreamLength, uint32(a.frames)},
+		{aviStreamBuffer, uint32(a.maxLen + 8)},
+		{aviMoviSize, uint32(moviEnd - aviMoviStart)},
+	}
+
+	for _, patch := range patches {
+		_, err := a.w.Seek(a.start+patch.offset, io.SeekStart)
+		if err == nil {
+			err = binary.Write(a.w, binary.LittleEndian, patch.value)
+		}
+		if err != nil {
+			Log.Error(err.Error())
+			return err
+		}
+	}
+
+	_, err = a.w.Seek(a.start+a.offset, io.SeekStart)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// header of the file up to the first frame, sizes and counts are patched by Close
+func (a *AVIWriter) header() []byte {
+
+	le := binary.LittleEndian
+	h := bytes.Buffer{}
+	rate := uint32(math.Round(a.fps * aviFrameRateFactor))
+
+	h.WriteString("RIFF")
+	binary.Write(&h, le, uint32(0))
+	h.WriteString("AVI ")
+
+	h.WriteString("LIST")
+	binary.Write(&h, le, uint32(192))
+	h.WriteString("hdrl")
+
+	h.WriteString("avih")
+	binary.Write(&h, le, uint32(56))
+	binary.Write(&h, le, []uint32{
+		uint32(math.Round(1e6 / a.fps)), // microseconds per frame
+		0,                               // max bytes per second
+		0,                               // padding granularity
+		aviHasIndex,                     // flags
+		0,                               // total frames
+		0,                               // initial frames
+		1,                               // streams
+		0,                               // suggested buffer size
+		uint32(a.width),
+		uint32(a.height),
+		0, 0, 0, 0,
+	})
+
+	h.WriteString("LIST")
+	binary.Write(&h, le, uint32(116))
+	h.WriteString("strl")
+
+	h.WriteString("strh")
+	binary.Write(&h, le, uint32(56))
+	h.WriteString("vidsMJPG")
+	binary.Write(&h, le, []uint32{
+		0,                  // flags
+		0,                  // priority and language
+		0,                  // initial frames
+		aviFrameRateFactor, // scale
+		rate,               // rate, frames per second is rate / scale
+		0,                  // start
+		0,                  // length in frames
+		0,                  // suggested buffer size
+		math.MaxUint32,     // quality, default
+		0,                  // sample size
+	})
+	binary.Write(&h, le, []uint16{0, 0, uint16(a.width), uint16(a.height)})
+
+	h.WriteString("strf")
+	binary.Write(&h, le, uint32(40))
+	binary.Write(&h, le, []uint32{40, uint32(a.width), uint32(a.height)})
+	binary.Write(&h, le, []uint16{1, 24})
+	h.WriteString("MJPG")
+	binary.Write(&h, le, []uint32{uint32(a.width * a.height * 3), 0, 0, 0, 0})
+
+	h.WriteString("LIST")
+	binary.Write(&h, le, uint32(0))
+	h.WriteString("movi")
+
+	return h.Bytes()
+}
+
+// write
+func (a *AVIWriter) write(data []byte) error {
+
+	n, err := a.w.Write(data)
+	a.offset += int64(n)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// RecordLiveView writes the live view to w as AVI until ctx is done, the camera fails or the file is full
+// the frame rate of the file is options.MaxFPS, 15 if not set, a full file returns ErrAVITooLarge
+func (c *Camera) RecordLiveView(ctx context.Context, w io.WriteSeeker, options LiveViewOptions) error {
+
+	if options.MaxFPS <= 0 {
+		options.MaxFPS = 15
+	}
+
+	avi, err := NewAVIWriter(w, options.MaxFPS)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	frames, err := c.LiveView(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	for frame := range frames {
+		err := frame.Err
+		if err == nil {
+			err = avi.WriteFrame(frame.Data)
+		}
+		if err != nil {
+			// wait for the live view to be closed
+			cancel()
+			for range frames {
+			}
+			// keep what was recorded so far playable
+			if avi.frames > 0 {
+				if closeErr := avi.Close(); closeErr != nil {
+					err = fmt.Errorf("%w, closing the file failed: %s", err, closeErr.Error())
+				}
+			}
+			return err
+		}
+	}
+
+	return avi.Close()
+}
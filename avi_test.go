@@ -0,0 +1,215 @@
+package gogp2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"testing"
+)
+
+// testJPEG encodes a plain image, odd gives a frame of odd length to exercise the chunk padding
+func testJPEG(t *testing.T, width int, height int, gray uint8, odd bool) []byte {
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = gray + uint8(i%7)
+	}
+
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, img, nil); err != nil {
+		t.Fatalf("encode JPEG: %v", err)
+	}
+
+	// decoders ignore bytes after the end of image marker
+	if (buffer.Len()%2 == 1) != odd {
+		buffer.WriteByte(0)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestAVIWriter(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		prefix int // bytes in the file before the AVI data
+		frames int
+		fps    float64
+	}{
+		{"single frame", 0, 1, 15},
+		{"several frames", 0, 4, 25},
+		{"after other data", 13, 3, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			file, err := os.CreateTemp(t.TempDir(), "*.avi")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			if _, err := file.Write(make([]byte, tt.prefix)); err != nil {
+				t.Fatal(err)
+			}
+
+			avi, err := NewAVIWriter(file, tt.fps)
+			if err != nil {
+				t.Fatalf("NewAVIWriter: %v", err)
+			}
+
+			var frames [][]byte
+			maxLen := 0
+			for i := 0; i < tt.frames; i++ {
+				frame := testJPEG(t, 64, 48, uint8(i*40), i%2 == 0)
+				if err := avi.WriteFrame(frame); err != nil {
+					t.Fatalf("WriteFrame: %v", err)
+				}
+				frames = append(frames, frame)
+				if len(frame) > maxLen {
+					maxLen = len(frame)
+				}
+			}
+
+			if err := avi.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			written, err := os.ReadFile(file.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := written[tt.prefix:]
+			le := binary.LittleEndian
+
+			if string(data[0:4]) != "RIFF" || string(data[8:12]) != "AVI " || string(data[aviMoviStart:aviMoviStart+4]) != "movi" {
+				t.Fatalf("not an AVI file: %q", data[:12])
+			}
+
+			fields := []struct {
+				name   string
+				offset int
+				want   uint32
+			}{
+				{"RIFF size", aviRiffSize, uint32(len(data) - 8)},
+				{"total frames", aviTotalFrames, uint32(tt.frames)},
+				{"main buffer size", aviMainBufferSize, uint32(maxLen + 8)},
+				{"width", 64, 64},
+				{"height", 68, 48},
+				{"stream length", aviStreamLength, uint32(tt.frames)},
+				{"stream buffer size", aviStreamBuffer, uint32(maxLen + 8)},
+			}
+			for _, f := range fields {
+				if got := le.Uint32(data[f.offset:]); got != f.want {
+					t.Errorf("%s = %d, want %d", f.name, got, f.want)
+				}
+			}
+
+			// the movi list is followed by the index
+			moviEnd := aviMoviStart + int(le.Uint32(data[aviMoviSize:]))
+			if moviEnd+8 > len(data) || string(data[moviEnd:moviEnd+4]) != "idx1" {
+				t.Fatalf("no idx1 chunk at the end of the movi list (%d)", moviEnd)
+			}
+			index := data[moviEnd+8:]
+			if got := int(le.Uint32(data[moviEnd+4:])); got != len(index) || got != 16*tt.frames {
+				t.Fatalf("idx1 size = %d, want %d", got, 16*tt.frames)
+			}
+
+			// offsets are relative to the movi fourcc, chunks are padded to an even size
+			want := uint32(4)
+			for i, frame := range frames {
+				entry := index[i*16:]
+				offset, size := le.Uint32(entry[8:]), le.Uint32(entry[12:])
+				if string(entry[:4]) != "00dc" || le.Uint32(entry[4:]) != aviKeyFrame {
+					t.Errorf("frame %d: index entry %q flags %#x", i, entry[:4], le.Uint32(entry[4:]))
+				}
+				if offset != want || size != uint32(len(frame)) {
+					t.Errorf("frame %d: index offset, size = %d, %d, want %d, %d", i, offset, size, want, len(frame))
+				}
+
+				chunk := data[aviMoviStart+int(offset):]
+				if string(chunk[:4]) != "00dc" || le.Uint32(chunk[4:]) != uint32(len(frame)) || !bytes.Equal(chunk[8:8+len(frame)], frame) {
+					t.Errorf("frame %d: chunk at offset %d does not hold the frame", i, offset)
+				}
+
+				want += 8 + uint32(len(frame)+len(frame)%2)
+			}
+			if int(want) != moviEnd-aviMoviStart {
+				t.Errorf("frames end at %d, movi list at %d", want, moviEnd-aviMoviStart)
+			}
+		})
+	}
+}
+
+func TestAVIWriterInvalid(t *testing.T) {
+
+	file, err := os.CreateTemp(t.TempDir(), "*.avi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := NewAVIWriter(file, 0); err == nil {
+		t.Error("NewAVIWriter accepted a frame rate of 0")
+	}
+
+	avi, err := NewAVIWriter(file, 15)
+	if err != nil {
+		t.Fatalf("NewAVIWriter: %v", err)
+	}
+	if err := avi.WriteFrame([]byte("not a JPEG image")); err == nil {
+		t.Error("WriteFrame accepted a frame which is not a JPEG image")
+	}
+	if err := avi.Close(); err == nil {
+		t.Error("Close succeeded without frames")
+	}
+}
+
+func TestAVIWriterTooLarge(t *testing.T) {
+
+	file, err := os.CreateTemp(t.TempDir(), "*.avi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	avi, err := NewAVIWriter(file, 15)
+	if err != nil {
+		t.Fatalf("NewAVIWriter: %v", err)
+	}
+
+	frame := testJPEG(t, 64, 48, 0, false)
+	frameSize := int64(8 + len(frame) + 16)
+	avi.maxSize = 224 + 3*frameSize + 8
+
+	for i := 0; i < 3; i++ {
+		if err := avi.WriteFrame(frame); err != nil {
+			t.Fatalf("frame %d: WriteFrame: %v", i, err)
+		}
+	}
+	if err := avi.WriteFrame(frame); err != ErrAVITooLarge {
+		t.Fatalf("WriteFrame past the limit: error = %v, want ErrAVITooLarge", err)
+	}
+	if err := avi.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != avi.maxSize {
+		t.Errorf("file size = %d, want %d", info.Size(), avi.maxSize)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.LittleEndian.Uint32(data[aviTotalFrames:]); got != 3 {
+		t.Errorf("total frames = %d, want 3", got)
+	}
+}
@@ -0,0 +1,217 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// BoothState is a step of a photo booth session
+type BoothState string
+
+// photo booth states
+const (
+	BoothCountdown   BoothState = "countdown"
+	BoothCapturing   BoothState = "capturing"
+	BoothDownloaded  BoothState = "downloaded"
+	BoothCompositing BoothState = "compositing"
+	BoothDone        BoothState = "done"
+	BoothFailed      BoothState = "failed"
+)
+
+// BoothOptions
+type BoothOptions struct {
+	Frames    int           // frames per session, 4 by default
+	Countdown int           // seconds counted down before each frame, 3 by default
+	Pause     time.Duration // pause after each frame, so the guests can see it
+	Strip     *StripLayout  // composite of all frames, nil disables it
+}
+
+// StripLayout places the frames of a session one below the other
+type StripLayout struct {
+	Width      int         // width of a frame in the strip in pixels, 600 by default
+	Margin     int         // space around the frames in pixels
+	Background color.Color // white by default
+	Quality    int         // JPEG quality, 90 by default
+}
+
+// BoothEvent reports a state change of a session
+type BoothEvent struct {
+	State     BoothState
+	Frame     int      // number of the frame the state refers to
+	Countdown int      // seconds left, in BoothCountdown
+	Data      []byte   // the frame in BoothDownloaded, the strip in BoothDone
+	Frames    [][]byte // all frames in BoothDone
+	Err       error    // in BoothFailed
+}
+
+// PhotoBooth runs one session in the background, the channel is closed after BoothDone or BoothFailed
+func (c *Camera) PhotoBooth(ctx context.Context, options BoothOptions) <-chan BoothEvent {
+
+	if options.Frames <= 0 {
+		options.Frames = 4
+	}
+	if options.Countdown <= 0 {
+		options.Countdown = 3
+	}
+
+	events := make(chan BoothEvent)
+
+	go func() {
+		defer close(events)
+
+		emit := func(event BoothEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		fail := func(err error) {
+			Log.Error(err.Error())
+			emit(BoothEvent{State: BoothFailed, Err: err})
+		}
+
+		frames := [][]byte{}
+		for i := 0; i < options.Frames; i++ {
+
+			for left := options.Countdown; left > 0; left-- {
+				if !emit(BoothEvent{State: BoothCountdown, Frame: i, Countdown: left}) {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+			}
+
+			if !emit(BoothEvent{State: BoothCapturing, Frame: i}) {
+				return
+			}
+
+			var buffer bytes.Buffer
+			err := c.CapturePhoto(&buffer)
+			if err != nil {
+				fail(err)
+				return
+			}
+			frames = append(frames, buffer.Bytes())
+
+			if !emit(BoothEvent{State: BoothDownloaded, Frame: i, Data: buffer.Bytes()}) {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(options.Pause):
+			}
+		}
+
+		done := BoothEvent{State: BoothDone, Frames: frames}
+
+		if options.Strip != nil {
+			if !emit(BoothEvent{State: BoothCompositing}) {
+				return
+			}
+			strip, err := ComposeStrip(frames, *options.Strip)
+			if err != nil {
+				fail(err)
+				return
+			}
+			done.Data = strip
+		}
+
+		emit(done)
+	}()
+
+	return events
+}
+
+// ComposeStrip places JPEG frames one below the other and returns the strip as JPEG
+func ComposeStrip(frames [][]byte, layout StripLayout) ([]byte, error) {
+
+	if layout.Width <= 0 {
+		layout.Width = 600
+	}
+	if layout.Background == nil {
+		layout.Background = color.White
+	}
+	if layout.Quality <= 0 {
+		layout.Quality = 90
+	}
+
+	scaled := []image.Image{}
+	height := layout.Margin
+	for _, frame := range frames {
+		img, err := DecodeFrame(frame)
+		if err != nil {
+			return nil, err
+		}
+		b := img.Bounds()
+		h := b.Dy() * layout.Width / b.Dx()
+		scaled = append(scaled, scaleImage(img, layout.Width, h))
+		height += h + layout.Margin
+	}
+
+	strip := image.NewRGBA(image.Rect(0, 0, layout.Width+2*layout.Margin, height))
+	draw.Draw(strip, strip.Bounds(), image.NewUniform(layout.Background), image.Point{}, draw.Src)
+
+	y := layout.Margin
+	for _, img := range scaled {
+		r := image.Rect(layout.Margin, y, layout.Margin+layout.Width, y+img.Bounds().Dy())
+		draw.Draw(strip, r, img, image.Point{}, draw.Src)
+		y += img.Bounds().Dy() + layout.Margin
+	}
+
+	var buffer bytes.Buffer
+	err := jpeg.Encode(&buffer, strip, &jpeg.Options{Quality: layout.Quality})
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// scaleImage resizes img to w x h, averaging the source pixels of each target pixel
+func scaleImage(img image.Image, w int, h int) *image.RGBA {
+
+	src := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := src.Min.Y + (y+1)*src.Dy()/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := src.Min.X + (x+1)*src.Dx()/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+cr>>8, g+cg>>8, b+cb>>8, a+ca>>8
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,151 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	Log "github.com/qazf88/golog"
+)
+
+// ErrBracketRange is returned when the camera offers fewer distinct values than the bracketing steps
+var ErrBracketRange = errors.New("bracketing range exceeds the camera choices")
+
+// BracketSpec describes an exposure bracketing sequence
+type BracketSpec struct {
+	Steps     int               // number of frames, centered on the current value
+	EVSpacing float64           // distance between two frames in stops
+	Parameter ExposureParameter // exposure parameter to bracket, shutter speed by default
+}
+
+// BracketFrame is a single frame of a bracketing sequence
+type BracketFrame struct {
+	Value string  // widget value used for the frame
+	EV    float64 // offset from the original exposure in stops
+	Data  []byte  // downloaded image
+}
+
+// BracketResult is a whole bracketing sequence
+type BracketResult struct {
+	Parameter ExposureParameter
+	Widget    string
+	Original  string
+	Frames    []BracketFrame
+}
+
+// Bracket captures spec.Steps frames around the current exposure and restores the original value afterwards
+func (c *Camera) Bracket(ctx context.Context, spec BracketSpec) (*BracketResult, error) {
+
+	if spec.Steps < 1 {
+		err := fmt.Sprintf("invalid bracketing steps: %d", spec.Steps)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	if !(spec.EVSpacing > 0) {
+		err := fmt.Sprintf("invalid bracketing spacing: %g", spec.EVSpacing)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	if spec.Parameter == "" {
+		spec.Parameter = ExposureShutter
+	}
+
+	wName, err := c.exposureWidgetName(spec.Parameter)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := c.GetWidgetValueByName(wName)
+	if err != nil {
+		return nil, err
+	}
+
+	base, ok := exposureValue(spec.Parameter, original)
+	if !ok {
+		err := fmt.Sprintf("cannot bracket widget by name '%s' with value '%s'", wName, original)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	choices, err := c.GetWidgetChoicesByName(wName)
+	if err != nil {
+		return nil, err
+	}
+
+	// all values are chosen before the first capture, a sequence the camera cannot cover is not started
+	values, evs, err := bracketValues(spec, choices, base)
+	if err != nil {
+		err = fmt.Errorf("%w around '%s' on widget by name '%s'", err, original, wName)
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	result := &BracketResult{
+		Parameter: spec.Parameter,
+		Widget:    wName,
+		Original:  original,
+	}
+
+	defer func() {
+		err := c.SetWigetValueByName(wName, original)
+		if err != nil {
+			Log.Error(fmt.Sprintf("could not restore widget by name '%s': %s", wName, err.Error()))
+		}
+	}()
+
+	for i, value := range values {
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		err := c.SetWigetValueByName(wName, value)
+		if err != nil {
+			return result, err
+		}
+
+		var buffer bytes.Buffer
+		err = c.CapturePhoto(&buffer)
+		if err != nil {
+			return result, err
+		}
+
+		result.Frames = append(result.Frames, BracketFrame{
+			Value: value,
+			EV:    evs[i] - base,
+			Data:  buffer.Bytes(),
+		})
+	}
+
+	return result, nil
+}
+
+// bracketValues picks the choice closest to every step of spec around base
+func bracketValues(spec BracketSpec, choices []string, base float64) ([]string, []float64, error) {
+
+	values := make([]string, 0, spec.Steps)
+	evs := make([]float64, 0, spec.Steps)
+	used := map[string]bool{}
+
+	for i := 0; i < spec.Steps; i++ {
+
+		offset := (float64(i) - float64(spec.Steps-1)/2) * spec.EVSpacing
+		value, ev, ok := nearestChoice(spec.Parameter, choices, base+offset)
+		if !ok {
+			return nil, nil, fmt.Errorf("no usable choices")
+		}
+
+		if used[value] {
+			return nil, nil, fmt.Errorf("%w: %d steps of %g stops", ErrBracketRange, spec.Steps, spec.EVSpacing)
+		}
+		used[value] = true
+
+		values = append(values, value)
+		evs = append(evs, ev)
+	}
+
+	return values, evs, nil
+}
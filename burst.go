@@ -0,0 +1,201 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// burst widgets
+const (
+	remoteReleaseWidget = "eosremoterelease"
+	releasePressFull    = "Press Full"
+	releaseFull         = "Release Full"
+)
+
+// widgets selecting single or continuous drive
+var driveModeWidgets = []string{"drivemode", "capturemode", "stillcapturemode"}
+
+// time to wait for the next file of a burst
+const burstFileTimeout = 10 * time.Second
+
+// time to wait for files still buffered in the camera after the release
+const burstDrainTimeout = 3 * time.Second
+
+// BurstFile is a single file of a burst
+type BurstFile struct {
+	Sequence int
+	Path     CameraFilePath
+	Data     []byte
+}
+
+// Burst captures n frames in continuous drive and passes each file to consumer as soon as it arrives
+// n < 1 holds the release until ctx is done, use context.WithTimeout for a burst of a fixed duration
+// at most n files are passed, frames the camera still writes after the n-th one stay on the camera
+func (c *Camera) Burst(ctx context.Context, n int, consumer func(BurstFile) error) error {
+
+	restore, err := c.setContinuousDrive()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	sequence := 0
+	deliver := func(path *CameraFilePath) error {
+		var buffer bytes.Buffer
+		err := c.DownloadImage(&buffer, path, true)
+		if err != nil {
+			return err
+		}
+		file := BurstFile{Sequence: sequence, Path: *path, Data: buffer.Bytes()}
+		sequence++
+		return consumer(file)
+	}
+	done := func() bool {
+		return (n > 0 && sequence >= n) || ctx.Err() != nil
+	}
+	// a cancelled context is an error only for a burst of a fixed number of frames
+	incomplete := func() error {
+		if n > 0 && sequence < n {
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	if _, err := c.getGpWidgetByName(remoteReleaseWidget); err != nil {
+		// no way to hold the release, fire one frame after another
+		for !done() {
+			err := c.triggerCapture()
+			if err != nil {
+				return err
+			}
+			path, err := c.waitForFile(ctx, burstFileTimeout)
+			if err != nil && ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				Log.Error(err.Error())
+				return err
+			}
+			err = deliver(path)
+			if err != nil {
+				return err
+			}
+		}
+		return incomplete()
+	}
+
+	err = c.SetWigetValueByName(remoteReleaseWidget, releasePressFull)
+	if err != nil {
+		return err
+	}
+
+	var burstErr error
+	for !done() {
+		// a cancelled ctx ends the wait, the release is let go right away
+		path, err := c.waitForFile(ctx, burstFileTimeout)
+		if err != nil && ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			burstErr = err
+			break
+		}
+		err = deliver(path)
+		if err != nil {
+			burstErr = err
+			break
+		}
+	}
+
+	err = c.SetWigetValueByName(remoteReleaseWidget, releaseFull)
+	if err != nil {
+		Log.Error(err.Error())
+		if burstErr == nil {
+			burstErr = err
+		}
+	}
+
+	// frames still in the camera buffer are delivered up to n, also after ctx is done
+	for burstErr == nil {
+		path, err := c.waitForFile(context.Background(), burstDrainTimeout)
+		if err == errEventTimeout {
+			break
+		}
+		if err != nil {
+			burstErr = err
+			break
+		}
+		if n > 0 && sequence >= n {
+			Log.Warning(fmt.Sprintf("burst frame past %d left on the camera: %s", n, joinPath(*path)))
+			continue
+		}
+		burstErr = deliver(path)
+	}
+
+	if burstErr != nil {
+		Log.Error(burstErr.Error())
+		return burstErr
+	}
+
+	return incomplete()
+}
+
+// setContinuousDrive switches the drive mode to continuous and returns a function restoring the old mode
+func (c *Camera) setContinuousDrive() (func(), error) {
+
+	for _, name := range driveModeWidgets {
+		_widget, err := c.getWidgetByName(name)
+		if err != nil {
+			continue
+		}
+
+		choice := continuousChoice(_widget.Choice)
+		if choice == "" {
+			continue
+		}
+
+		old := _widget.Value
+		err = c.SetWigetValueByName(name, choice)
+		if err != nil {
+			return nil, err
+		}
+
+		wName := name
+		return func() {
+			err := c.SetWigetValueByName(wName, old)
+			if err != nil {
+				Log.Error(fmt.Sprintf("could not restore drive mode: %s", err.Error()))
+			}
+		}, nil
+	}
+
+	return nil, ErrNotSupported
+}
+
+// continuousChoice picks the fastest continuous drive mode
+func continuousChoice(choices []string) string {
+
+	found := ""
+	for _, choice := range choices {
+		lower := strings.ToLower(choice)
+		if !strings.Contains(lower, "continuous") && !strings.Contains(lower, "burst") {
+			continue
+		}
+		if strings.Contains(lower, "timer") {
+			continue
+		}
+		if strings.Contains(lower, "high") {
+			return choice
+		}
+		if found == "" {
+			found = choice
+		}
+	}
+
+	return found
+}
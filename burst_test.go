@@ -0,0 +1,27 @@
+package gogp2
+
+import "testing"
+
+func TestContinuousChoice(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		choices []string
+		want    string
+	}{
+		{"Canon", []string{"Single", "Continuous high speed", "Continuous low speed", "Timer 10 sec"}, "Continuous high speed"},
+		{"first continuous", []string{"Single Shot", "Continuous Low Speed", "Continuous"}, "Continuous Low Speed"},
+		{"burst", []string{"Single Shot", "Burst"}, "Burst"},
+		{"timer skipped", []string{"Single", "Continuous Timer"}, ""},
+		{"none", []string{"Single", "Self-timer"}, ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := continuousChoice(tt.choices); got != tt.want {
+				t.Errorf("continuousChoice = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,9 @@ import (
 // Model
 func (c *Camera) Model() (string, error) {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	var abilities C.CameraAbilities
 	res := C.gp_camera_get_abilities(c.Camera, &abilities)
 	if res != OK {
@@ -37,9 +40,32 @@ func (c *Camera) Init() error {
 			return err
 		}
 	} else {
+		// not under c.mu, cancelling is meant to interrupt a running operation
 		C.gp_context_cancel(c.Context)
 	}
 
+	err := c.resetCamera()
+	if err != nil {
+		return err
+	}
+
+	err = c.InitCamera()
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	Log.Info("New camera")
+
+	return nil
+}
+
+// resetCamera releases the current camera and allocates a new one
+func (c *Camera) resetCamera() error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.Camera != nil {
 
 		res := C.gp_camera_exit(c.Camera, c.Context)
@@ -67,20 +93,15 @@ func (c *Camera) Init() error {
 
 	c.Camera = Camera
 
-	err := c.InitCamera()
-	if err != nil {
-		Log.Error(err.Error())
-		return err
-	}
-
-	Log.Info("New camera")
-
 	return nil
 }
 
 // NewCamera
 func (c *Camera) NewCamera() error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.Context == nil {
 		err := "could not get camera, context is empty"
 		Log.Error(err)
@@ -114,6 +135,9 @@ func (c *Camera) NewCamera() error {
 // InitCamera
 func (c *Camera) InitCamera() error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.Camera == nil {
 		err := "camera not avalible"
 		Log.Error(err)
@@ -142,6 +166,9 @@ func (c *Camera) InitCamera() error {
 // AvalibleCamera
 func (c *Camera) AvalibleCamera() bool {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	_, err := c.getRootWidget()
 	return err == nil
 }
@@ -149,6 +176,9 @@ func (c *Camera) AvalibleCamera() bool {
 // FreeCamera
 func (c *Camera) FreeCamera() error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	res := C.gp_camera_exit(c.Camera, c.Context)
 	if res != OK {
 		err := "error exit camera: " + strconv.Itoa(int(res))
@@ -162,6 +192,9 @@ func (c *Camera) FreeCamera() error {
 // UnrefCamera
 func (c *Camera) UnrefCamera() error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	res := C.gp_camera_unref(c.Camera)
 	if res != OK {
 		err := "error unref camera: " + strconv.Itoa(int(res))
@@ -175,6 +208,9 @@ func (c *Camera) UnrefCamera() error {
 // RefCamera
 func (c *Camera) RefCamera() error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	res := C.gp_camera_ref(c.Camera)
 	if res != OK {
 		err := fmt.Sprintf("error ref camera: %d", res)
@@ -188,6 +224,9 @@ func (c *Camera) RefCamera() error {
 // HardResetCameraConnection !!! Warning !!!
 func (c *Camera) HardResetCameraConnection() {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	Log.Info("Hard reset camera")
 
 	C.gp_camera_free(c.Camera)
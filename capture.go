@@ -7,6 +7,8 @@ package gogp2
 import "C"
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -18,6 +20,9 @@ import (
 // CaptureExternalEvent
 func (c *Camera) CaptureExternalEvent(timeout int, bufferOut io.Writer) error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	file, err := newFile()
 	if err != nil {
 		Log.Error(err.Error())
@@ -70,16 +75,32 @@ func (c *Camera) CaptureExternalEvent(timeout int, bufferOut io.Writer) error {
 
 }
 
-// CapturePhoto
+// CapturePhoto runs the capture through the middleware registered with Use
 func (c *Camera) CapturePhoto(buffer *bytes.Buffer) error {
 
+	handler := CaptureHandler(func(c *Camera, capture *Capture) error {
+		return c.capturePhoto(capture)
+	})
+	middleware := c.captureMiddleware()
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler(c, &Capture{Buffer: buffer})
+}
+
+// capturePhoto
+func (c *Camera) capturePhoto(capture *Capture) error {
+
 	type cameraFilePathInternal struct {
 		Name   [128]uint8
 		Folder [1024]uint8
 	}
 
 	photoPath := cameraFilePathInternal{}
+	c.mu.Lock()
 	res := C.gp_camera_capture(c.Camera, 0, (*C.CameraFilePath)(unsafe.Pointer(&photoPath)), c.Context)
+	c.mu.Unlock()
 	if res != OK {
 		if c.Camera != nil {
 			c.FreeCamera()
@@ -89,17 +110,26 @@ func (c *Camera) CapturePhoto(buffer *bytes.Buffer) error {
 		return fmt.Errorf(err)
 	}
 
-	buff := io.Writer(buffer)
+	buff := io.Writer(capture.Buffer)
 	filePath := &CameraFilePath{
 		Name:     string(photoPath.Name[:bytes.IndexByte(photoPath.Name[:], 0)]),
 		Folder:   string(photoPath.Folder[:bytes.IndexByte(photoPath.Folder[:], 0)]),
 		Isdir:    false,
 		Children: nil,
 	}
+	capture.Path = *filePath
 
+	before := capture.Buffer.Len()
 	err := c.DownloadImage(buff, filePath, true)
 	if err != nil {
 		Log.Error(err.Error())
+		return err
+	}
+
+	if capture.Buffer.Len() == before {
+		err := fmt.Sprintf("captured photo %s is empty", filePath.Name)
+		Log.Error(err)
+		return fmt.Errorf(err)
 	}
 
 	return nil
@@ -107,6 +137,9 @@ func (c *Camera) CapturePhoto(buffer *bytes.Buffer) error {
 
 func (c *Camera) CapturePreview(buffer io.Writer) error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	gpFile, err := newFile()
 	if err != nil {
 		return err
@@ -134,6 +167,9 @@ func (c *Camera) CapturePreview(buffer io.Writer) error {
 // CaptureCompletedEvent
 func (c *Camera) CaptureCompletedEvent(bufferOut io.Writer) error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	file, err := newFile()
 	if err != nil {
 		Log.Error(err.Error())
@@ -190,6 +226,9 @@ func (c *Camera) CaptureCompletedEvent(bufferOut io.Writer) error {
 // ClearIramFile
 func (c *Camera) ClearIramFile() {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	file, err := newFile()
 	if err != nil {
 		Log.Error(err.Error())
@@ -234,3 +273,94 @@ loop:
 		}
 	}
 }
+
+// errEventTimeout is returned when the camera reported no file in time
+var errEventTimeout = errors.New("timeout")
+
+// triggerCapture releases the shutter without waiting for the image
+func (c *Camera) triggerCapture() error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := C.gp_camera_trigger_capture(c.Camera, c.Context)
+	if res != OK {
+		err := fmt.Sprintf("cannot trigger capture, error code: %d", res)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	return nil
+}
+
+// longest single wait for a camera event, the camera is free for other calls and ctx is checked in between
+const eventWaitSlice = 200 * time.Millisecond
+
+// waitForFile waits for the next file added on the camera until timeout or ctx is done
+func (c *Camera) waitForFile(ctx context.Context, timeout time.Duration) (*CameraFilePath, error) {
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		left := time.Until(deadline)
+		if left <= 0 {
+			return nil, errEventTimeout
+		}
+		if left > eventWaitSlice {
+			left = eventWaitSlice
+		}
+
+		path, err := c.waitForFileEvent(left)
+		if err != nil || path != nil {
+			return path, err
+		}
+	}
+}
+
+// waitForFileEvent waits for camera events up to timeout, the path is nil if no file was added
+func (c *Camera) waitForFileEvent(timeout time.Duration) (*CameraFilePath, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		left := time.Until(deadline)
+		if left <= 0 {
+			return nil, nil
+		}
+
+		var eventType C.CameraEventType
+		var vp unsafe.Pointer
+
+		res := C.gp_camera_wait_for_event(c.Camera, C.int(left/time.Millisecond), &eventType, &vp, c.Context)
+		if res != OK {
+			err := fmt.Sprintf("error wait for event, error code: %d", res)
+			Log.Error(err)
+			return nil, fmt.Errorf(err)
+		}
+
+		if int(eventType) == EVENT_FILE_ADDED && vp != nil {
+			path := newCameraFilePath((*C.CameraFilePath)(vp))
+			C.free(vp)
+			return path, nil
+		}
+
+		if vp != nil {
+			C.free(vp)
+		}
+	}
+}
+
+// newCameraFilePath
+func newCameraFilePath(path *C.CameraFilePath) *CameraFilePath {
+	return &CameraFilePath{
+		Name:   C.GoString((*C.char)(&path.name[0])),
+		Folder: C.GoString((*C.char)(&path.folder[0])),
+	}
+}
@@ -13,6 +13,9 @@ import (
 // NewContext
 func (c *Camera) NewContext() error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.Context != nil {
 		err := "context is already initialized"
 		Log.Error(err)
@@ -34,6 +37,9 @@ func (c *Camera) NewContext() error {
 // FreeContext
 func (c *Camera) FreeContext() error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.Context != nil {
 		C.gp_context_unref(c.Context)
 		c = nil
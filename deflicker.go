@@ -0,0 +1,203 @@
+package gogp2
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	Log "github.com/qazf88/golog"
+)
+
+// DeflickerOptions
+type DeflickerOptions struct {
+	Window  int // frames averaged on each side of a frame, 5 by default
+	Quality int // JPEG quality of the written frames, 95 by default
+}
+
+// Deflicker evens out the brightness of the JPEG frames in srcDir and writes them to dstDir
+// frames are processed in name order, the mean luma of each frame is corrected to a moving average
+// frames needing no correction are copied unchanged, corrected frames keep the EXIF data of the original
+func Deflicker(srcDir string, dstDir string, options DeflickerOptions) error {
+
+	if options.Window <= 0 {
+		options.Window = 5
+	}
+	if options.Quality <= 0 {
+		options.Quality = 95
+	}
+
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".jpg" || ext == ".jpeg") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		err := fmt.Sprintf("no JPEG frames in %s", srcDir)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	brightness := make([]float64, len(names))
+	for i, name := range names {
+		img, err := readJPEG(filepath.Join(srcDir, name))
+		if err != nil {
+			return err
+		}
+		brightness[i] = NewHistogram(img).Mean()
+	}
+
+	smoothed := smoothCurve(brightness, options.Window)
+
+	err = os.MkdirAll(dstDir, 0755)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	for i, name := range names {
+		src := filepath.Join(srcDir, name)
+		data, err := os.ReadFile(src)
+		if err != nil {
+			Log.Error(err.Error())
+			return err
+		}
+
+		if needsCorrection(brightness[i], smoothed[i]) {
+			data, err = correctJPEG(src, data, brightness[i], smoothed[i], options.Quality)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = os.WriteFile(filepath.Join(dstDir, name), data, 0644)
+		if err != nil {
+			Log.Error(err.Error())
+			return err
+		}
+	}
+
+	Log.Info(fmt.Sprintf("deflickered %d frames", len(names)))
+	return nil
+}
+
+// correctJPEG re-encodes the frame with the corrected brightness and the APP1 EXIF segment of the original
+func correctJPEG(path string, data []byte, measured float64, target float64, quality int) ([]byte, error) {
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		err := fmt.Sprintf("cannot decode %s: %s", path, err.Error())
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	var buffer bytes.Buffer
+	err = jpeg.Encode(&buffer, adjustBrightness(img, measured, target), &jpeg.Options{Quality: quality})
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+	encoded := buffer.Bytes()
+
+	// the encoder writes no APP segments, the EXIF segment goes right after the start of image marker
+	exif := jpegEXIFSegment(data)
+	if exif == nil {
+		return encoded, nil
+	}
+
+	out := make([]byte, 0, len(encoded)+len(exif))
+	out = append(out, encoded[:2]...)
+	out = append(out, exif...)
+	out = append(out, encoded[2:]...)
+
+	return out, nil
+}
+
+// needsCorrection tells whether moving the mean luma from measured to target changes the frame
+func needsCorrection(measured float64, target float64) bool {
+	return measured > 0 && measured < 255 && target > 0 && target < 255 && math.Abs(target-measured) >= 0.5
+}
+
+// smoothCurve is a centered moving average of values
+func smoothCurve(values []float64, window int) []float64 {
+
+	smoothed := make([]float64, len(values))
+	for i := range values {
+		from, to := i-window, i+window
+		if from < 0 {
+			from = 0
+		}
+		if to > len(values)-1 {
+			to = len(values) - 1
+		}
+		sum := 0.0
+		for j := from; j <= to; j++ {
+			sum += values[j]
+		}
+		smoothed[i] = sum / float64(to-from+1)
+	}
+
+	return smoothed
+}
+
+// adjustBrightness moves the mean luma of img from measured to target with a gamma curve
+func adjustBrightness(img image.Image, measured float64, target float64) image.Image {
+
+	if !needsCorrection(measured, target) {
+		return img
+	}
+
+	// gamma mapping measured to target keeps black and white in place
+	gamma := math.Log(target/255) / math.Log(measured/255)
+	var curve [256]uint8
+	for i := range curve {
+		curve[i] = uint8(math.Round(255 * math.Pow(float64(i)/255, gamma)))
+	}
+
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			out.SetRGBA(x, y, color.RGBA{curve[r>>8], curve[g>>8], curve[bl>>8], uint8(a >> 8)})
+		}
+	}
+
+	return out
+}
+
+// readJPEG
+func readJPEG(path string) (image.Image, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		err := fmt.Sprintf("cannot decode %s: %s", path, err.Error())
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	return img, nil
+}
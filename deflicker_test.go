@@ -0,0 +1,114 @@
+package gogp2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSmoothCurve(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		values []float64
+		window int
+		want   []float64
+	}{
+		{"empty", nil, 2, []float64{}},
+		{"single", []float64{10}, 3, []float64{10}},
+		{"flat", []float64{5, 5, 5, 5}, 1, []float64{5, 5, 5, 5}},
+		{"window 1", []float64{0, 3, 6, 9}, 1, []float64{1.5, 3, 6, 7.5}},
+		{"flicker", []float64{10, 20, 10, 20, 10}, 1, []float64{15, 40.0 / 3, 50.0 / 3, 40.0 / 3, 15}},
+		{"window wider than values", []float64{1, 2, 3}, 5, []float64{2, 2, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := smoothCurve(tt.values, tt.window)
+			if len(got) != len(tt.want) {
+				t.Fatalf("smoothCurve = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("smoothCurve = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestDeflicker(t *testing.T) {
+
+	src, dst := t.TempDir(), t.TempDir()
+
+	// the middle frame is brighter than its neighbours, the first and last frames average to their own brightness
+	tiff := sampleTIFF(binary.LittleEndian, "N", "E", 0)
+	frames := map[string][]byte{
+		"a.jpg": testJPEG(t, 32, 32, 100, false),
+		"b.jpg": testJPEG(t, 32, 32, 100, false),
+		"c.jpg": withEXIF(testJPEG(t, 32, 32, 160, false), tiff),
+		"d.jpg": testJPEG(t, 32, 32, 100, false),
+		"e.jpg": testJPEG(t, 32, 32, 100, false),
+	}
+	for name, data := range frames {
+		if err := os.WriteFile(filepath.Join(src, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Deflicker(src, dst, DeflickerOptions{Window: 1}); err != nil {
+		t.Fatalf("Deflicker: %v", err)
+	}
+
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	for _, name := range []string{"a.jpg", "e.jpg"} {
+		if !bytes.Equal(read(name), frames[name]) {
+			t.Errorf("%s needs no correction but was re-encoded", name)
+		}
+	}
+
+	corrected := read("c.jpg")
+	if bytes.Equal(corrected, frames["c.jpg"]) {
+		t.Fatal("c.jpg was not corrected")
+	}
+	exif, err := ParseEXIF(corrected)
+	if err != nil {
+		t.Fatalf("corrected frame lost its EXIF data: %v", err)
+	}
+	if exif.Model != "Canon EOS R6" {
+		t.Errorf("model = %q, want the EXIF data of the original", exif.Model)
+	}
+	img, err := readJPEG(filepath.Join(dst, "c.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mean := NewHistogram(img).Mean(); mean >= 150 {
+		t.Errorf("corrected mean luma = %g, want darker than the original", mean)
+	}
+}
+
+// withEXIF inserts an APP1 EXIF segment after the start of image marker
+func withEXIF(jpegData []byte, tiff []byte) []byte {
+
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+
+	var out bytes.Buffer
+	out.Write(jpegData[:2])
+	out.Write([]byte{0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(app1)+2))
+	out.Write(app1)
+	out.Write(jpegData[2:])
+
+	return out.Bytes()
+}
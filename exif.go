@@ -0,0 +1,414 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// bytes read from the start of a file when the camera cannot provide the EXIF block alone
+const exifHeadSize = 256 << 10
+
+// EXIF tags
+const (
+	tagMake              = 0x010f
+	tagModel             = 0x0110
+	tagOrientation       = 0x0112
+	tagDateTime          = 0x0132
+	tagExifIFD           = 0x8769
+	tagGPSIFD            = 0x8825
+	tagExposureTime      = 0x829a
+	tagFNumber           = 0x829d
+	tagISO               = 0x8827
+	tagISOSpeed          = 0x8833
+	tagDateTimeOriginal  = 0x9003
+	tagOffsetTimeOrig    = 0x9011
+	tagFocalLength       = 0x920a
+	tagBodySerialNumber  = 0xa431
+	tagLensModel         = 0xa434
+	tagLensSerialNumber  = 0xa435
+	tagGPSLatitudeRef    = 0x0001
+	tagGPSLatitude       = 0x0002
+	tagGPSLongitudeRef   = 0x0003
+	tagGPSLongitude      = 0x0004
+	tagGPSAltitudeRef    = 0x0005
+	tagGPSAltitude       = 0x0006
+	tagGPSTimeStamp      = 0x0007
+	tagGPSDateStamp      = 0x001d
+	exifDateTimeLayout   = "2006:01:02 15:04:05"
+	exifDateOffsetLayout = "2006:01:02 15:04:05-07:00"
+)
+
+// size in bytes of the TIFF field types
+var tiffTypeSize = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8}
+
+// EXIF holds the capture information of an image, fields missing in the file are left empty
+type EXIF struct {
+	Make         string    `json:"make"`
+	Model        string    `json:"model"`
+	DateTime     time.Time `json:"dateTime"`     // capture time, DateTimeOriginal if present
+	ExposureTime float64   `json:"exposureTime"` // seconds
+	FNumber      float64   `json:"fNumber"`
+	ISO          int       `json:"iso"`
+	FocalLength  float64   `json:"focalLength"` // millimeters
+	LensModel    string    `json:"lensModel"`
+	SerialNumber string    `json:"serialNumber"`
+	LensSerial   string    `json:"lensSerial"`
+	Orientation  int       `json:"orientation"` // 1..8, 0 if unknown
+	GPS          *GPSInfo  `json:"gps,omitempty"`
+}
+
+// GPSInfo
+type GPSInfo struct {
+	Latitude  float64   `json:"latitude"`  // degrees, north positive
+	Longitude float64   `json:"longitude"` // degrees, east positive
+	Altitude  float64   `json:"altitude"`  // meters above sea level
+	Time      time.Time `json:"time"`      // UTC
+}
+
+// Shutter formats the exposure time like the camera does, "1/125" or "2"
+func (e *EXIF) Shutter() string {
+
+	if e.ExposureTime <= 0 {
+		return ""
+	}
+	if e.ExposureTime < 1 {
+		return fmt.Sprintf("1/%d", int(math.Round(1/e.ExposureTime)))
+	}
+	return fmt.Sprintf("%g", e.ExposureTime)
+}
+
+// EXIF downloads and parses the EXIF data of a file on the camera
+func (c *Camera) EXIF(ctx context.Context, path CameraFilePath) (*EXIF, error) {
+
+	var buffer bytes.Buffer
+	err := c.DownloadFile(ctx, path, FileTypeExif, &buffer)
+	if err == nil {
+		exif, err := ParseEXIF(buffer.Bytes())
+		if err == nil {
+			return exif, nil
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// the EXIF block is at the start of JPEG and TIFF based RAW files
+	head := make([]byte, exifHeadSize)
+	n, err := c.readFileAt(&path, FileTypeNormal, 0, head)
+	if err == errPartialReadNotSupported {
+		buffer.Reset()
+		_, err = c.downloadWholeFile(&path, FileTypeNormal, 0, &buffer)
+		head, n = buffer.Bytes(), buffer.Len()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseEXIF(head[:n])
+}
+
+// ParseEXIF reads EXIF data from a JPEG image, an APP1 EXIF block or a TIFF based file such as CR2, NEF or DNG
+func ParseEXIF(data []byte) (*EXIF, error) {
+
+	tiff, err := findTIFF(data)
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	r, err := newTIFFReader(tiff)
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	ifd0, err := r.ifd(r.order.Uint32(tiff[4:]))
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	exif := &EXIF{
+		Make:        ifd0.ascii(tagMake),
+		Model:       ifd0.ascii(tagModel),
+		Orientation: int(ifd0.uint(tagOrientation)),
+	}
+	exif.DateTime = parseEXIFTime(ifd0.ascii(tagDateTime), "")
+
+	if offset := ifd0.uint(tagExifIFD); offset != 0 {
+		sub, err := r.ifd(offset)
+		if err != nil {
+			Log.Error(err.Error())
+			return nil, err
+		}
+
+		exif.ExposureTime = sub.rational(tagExposureTime, 0)
+		exif.FNumber = sub.rational(tagFNumber, 0)
+		exif.FocalLength = sub.rational(tagFocalLength, 0)
+		exif.ISO = int(sub.uint(tagISO))
+		if iso := sub.uint(tagISOSpeed); iso != 0 && (exif.ISO == 0 || exif.ISO == math.MaxUint16) {
+			exif.ISO = int(iso)
+		}
+		exif.LensModel = sub.ascii(tagLensModel)
+		exif.SerialNumber = sub.ascii(tagBodySerialNumber)
+		exif.LensSerial = sub.ascii(tagLensSerialNumber)
+
+		if original := parseEXIFTime(sub.ascii(tagDateTimeOriginal), sub.ascii(tagOffsetTimeOrig)); !original.IsZero() {
+			exif.DateTime = original
+		}
+	}
+
+	if offset := ifd0.uint(tagGPSIFD); offset != 0 {
+		gps, err := r.ifd(offset)
+		if err == nil && gps.has(tagGPSLatitude) && gps.has(tagGPSLongitude) {
+			info := &GPSInfo{
+				Latitude:  gps.degrees(tagGPSLatitude),
+				Longitude: gps.degrees(tagGPSLongitude),
+				Altitude:  gps.rational(tagGPSAltitude, 0),
+			}
+			if gps.ascii(tagGPSLatitudeRef) == "S" {
+				info.Latitude = -info.Latitude
+			}
+			if gps.ascii(tagGPSLongitudeRef) == "W" {
+				info.Longitude = -info.Longitude
+			}
+			if gps.uint(tagGPSAltitudeRef) == 1 {
+				info.Altitude = -info.Altitude
+			}
+			if date, err := time.Parse("2006:01:02", gps.ascii(tagGPSDateStamp)); err == nil && gps.has(tagGPSTimeStamp) {
+				seconds := gps.rational(tagGPSTimeStamp, 0)*3600 + gps.rational(tagGPSTimeStamp, 1)*60 + gps.rational(tagGPSTimeStamp, 2)
+				info.Time = date.Add(time.Duration(seconds * float64(time.Second)))
+			}
+			exif.GPS = info
+		}
+	}
+
+	return exif, nil
+}
+
+// findTIFF returns the TIFF structure holding the EXIF data
+func findTIFF(data []byte) ([]byte, error) {
+
+	if bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+		return data[6:], nil
+	}
+
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		return data, nil
+	}
+
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return nil, fmt.Errorf("no EXIF data: unknown file format")
+	}
+
+	segment := jpegEXIFSegment(data)
+	if segment == nil {
+		return nil, fmt.Errorf("no EXIF data in JPEG image")
+	}
+
+	return segment[10:], nil
+}
+
+// jpegEXIFSegment returns the whole APP1 EXIF segment of a JPEG image, marker included, nil if there is none
+func jpegEXIFSegment(data []byte) []byte {
+
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return nil
+	}
+
+	// walk the segments up to the APP1 EXIF segment
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			break
+		}
+		marker := data[i+1]
+		if marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) || marker == 0xff {
+			i++
+			if marker != 0xff {
+				i++
+			}
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		if marker == 0xe1 && bytes.HasPrefix(data[i+4:end], []byte("Exif\x00\x00")) {
+			return data[i:end]
+		}
+		i = end
+	}
+
+	return nil
+}
+
+// tiffReader
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// newTIFFReader
+func newTIFFReader(data []byte) (*tiffReader, error) {
+
+	if len(data) < 8 {
+		return nil, fmt.Errorf("invalid EXIF data: too short")
+	}
+
+	switch string(data[:2]) {
+	case "II":
+		return &tiffReader{data: data, order: binary.LittleEndian}, nil
+	case "MM":
+		return &tiffReader{data: data, order: binary.BigEndian}, nil
+	}
+
+	return nil, fmt.Errorf("invalid EXIF data: unknown byte order")
+}
+
+// tiffEntry is a field of an IFD
+type tiffEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// tiffIFD
+type tiffIFD struct {
+	order   binary.ByteOrder
+	entries map[uint16]tiffEntry
+}
+
+// ifd reads the image file directory at offset
+func (r *tiffReader) ifd(offset uint32) (*tiffIFD, error) {
+
+	if uint64(offset)+2 > uint64(len(r.data)) {
+		return nil, fmt.Errorf("invalid EXIF data: directory offset %d out of range", offset)
+	}
+
+	count := uint32(r.order.Uint16(r.data[offset:]))
+	if uint64(offset)+2+uint64(count)*12 > uint64(len(r.data)) {
+		return nil, fmt.Errorf("invalid EXIF data: directory at %d truncated", offset)
+	}
+
+	ifd := &tiffIFD{order: r.order, entries: map[uint16]tiffEntry{}}
+	for i := uint32(0); i < count; i++ {
+		e := r.data[offset+2+i*12:]
+		tag := r.order.Uint16(e)
+		typ := r.order.Uint16(e[2:])
+		n := r.order.Uint32(e[4:])
+
+		size, ok := tiffTypeSize[typ]
+		if !ok {
+			continue
+		}
+		total := uint64(size) * uint64(n)
+
+		value := e[8:12]
+		if total > 4 {
+			start := uint64(r.order.Uint32(e[8:]))
+			if start+total > uint64(len(r.data)) {
+				continue
+			}
+			value = r.data[start : start+total]
+		}
+
+		ifd.entries[tag] = tiffEntry{typ: typ, count: n, value: value[:total]}
+	}
+
+	return ifd, nil
+}
+
+// has
+func (d *tiffIFD) has(tag uint16) bool {
+	_, ok := d.entries[tag]
+	return ok
+}
+
+// ascii returns a string field without trailing NULs and spaces
+func (d *tiffIFD) ascii(tag uint16) string {
+
+	e, ok := d.entries[tag]
+	if !ok || (e.typ != 2 && e.typ != 7) {
+		return ""
+	}
+
+	return strings.TrimRight(string(e.value), "\x00 ")
+}
+
+// uint returns the first value of a BYTE, SHORT or LONG field, 0 if missing
+func (d *tiffIFD) uint(tag uint16) uint32 {
+
+	e, ok := d.entries[tag]
+	if !ok || e.count == 0 {
+		return 0
+	}
+
+	switch e.typ {
+	case 1:
+		return uint32(e.value[0])
+	case 3:
+		return uint32(d.order.Uint16(e.value))
+	case 4:
+		return d.order.Uint32(e.value)
+	}
+
+	return 0
+}
+
+// rational returns the i-th value of a RATIONAL or SRATIONAL field, 0 if missing
+func (d *tiffIFD) rational(tag uint16, i uint32) float64 {
+
+	e, ok := d.entries[tag]
+	if !ok || i >= e.count || (e.typ != 5 && e.typ != 10) {
+		return 0
+	}
+
+	num := d.order.Uint32(e.value[i*8:])
+	den := d.order.Uint32(e.value[i*8+4:])
+	if den == 0 {
+		return 0
+	}
+
+	if e.typ == 10 {
+		return float64(int32(num)) / float64(int32(den))
+	}
+	return float64(num) / float64(den)
+}
+
+// degrees converts a GPS degrees, minutes, seconds field
+func (d *tiffIFD) degrees(tag uint16) float64 {
+	return d.rational(tag, 0) + d.rational(tag, 1)/60 + d.rational(tag, 2)/3600
+}
+
+// parseEXIFTime reads "2006:01:02 15:04:05" in the zone of offset, the local zone if offset is empty
+func parseEXIFTime(value string, offset string) time.Time {
+
+	if value == "" {
+		return time.Time{}
+	}
+
+	if offset != "" {
+		if t, err := time.Parse(exifDateOffsetLayout, value+offset); err == nil {
+			return t
+		}
+	}
+
+	t, err := time.ParseInLocation(exifDateTimeLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
@@ -0,0 +1,303 @@
+package gogp2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// tiffField is a field written by buildIFD
+type tiffField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiField(tag uint16, value string) tiffField {
+	return tiffField{tag: tag, typ: 2, count: uint32(len(value) + 1), data: append([]byte(value), 0)}
+}
+
+func shortField(order binary.ByteOrder, tag uint16, value uint16) tiffField {
+	data := make([]byte, 2)
+	order.PutUint16(data, value)
+	return tiffField{tag: tag, typ: 3, count: 1, data: data}
+}
+
+func longField(order binary.ByteOrder, tag uint16, value uint32) tiffField {
+	data := make([]byte, 4)
+	order.PutUint32(data, value)
+	return tiffField{tag: tag, typ: 4, count: 1, data: data}
+}
+
+// rationalField takes numerator, denominator pairs
+func rationalField(order binary.ByteOrder, tag uint16, values ...uint32) tiffField {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		order.PutUint32(data[i*4:], v)
+	}
+	return tiffField{tag: tag, typ: 5, count: uint32(len(values) / 2), data: data}
+}
+
+// buildIFD lays out an IFD starting at offset, values longer than 4 bytes follow the directory
+func buildIFD(order binary.ByteOrder, offset uint32, fields []tiffField) []byte {
+
+	size := 2 + 12*len(fields) + 4
+	out := make([]byte, size)
+	order.PutUint16(out, uint16(len(fields)))
+
+	for i, f := range fields {
+		e := out[2+i*12:]
+		order.PutUint16(e, f.tag)
+		order.PutUint16(e[2:], f.typ)
+		order.PutUint32(e[4:], f.count)
+		if len(f.data) <= 4 {
+			copy(e[8:12], f.data)
+			continue
+		}
+		order.PutUint32(e[8:], offset+uint32(len(out)))
+		out = append(out, f.data...)
+		if len(out)%2 == 1 {
+			out = append(out, 0)
+		}
+	}
+
+	return out
+}
+
+// buildTIFF writes IFD0 and the optional EXIF and GPS directories it points to
+func buildTIFF(order binary.ByteOrder, ifd0 []tiffField, exif []tiffField, gps []tiffField) []byte {
+
+	header := []byte("II*\x00\x08\x00\x00\x00")
+	if order == binary.BigEndian {
+		header = []byte("MM\x00*\x00\x00\x00\x08")
+	}
+
+	// the pointer fields fit in the directory, the size of IFD0 does not depend on their values
+	pointers := func(exifOffset, gpsOffset uint32) []tiffField {
+		fields := append([]tiffField{}, ifd0...)
+		if exif != nil {
+			fields = append(fields, longField(order, tagExifIFD, exifOffset))
+		}
+		if gps != nil {
+			fields = append(fields, longField(order, tagGPSIFD, gpsOffset))
+		}
+		return fields
+	}
+
+	first := buildIFD(order, 8, pointers(0, 0))
+	exifOffset := uint32(8 + len(first))
+	exifIFD := buildIFD(order, exifOffset, exif)
+	gpsOffset := exifOffset + uint32(len(exifIFD))
+	gpsIFD := buildIFD(order, gpsOffset, gps)
+
+	out := append([]byte{}, header...)
+	out = append(out, buildIFD(order, 8, pointers(exifOffset, gpsOffset))...)
+	if exif != nil {
+		out = append(out, exifIFD...)
+	}
+	if gps != nil {
+		out = append(out, gpsIFD...)
+	}
+
+	return out
+}
+
+// sampleTIFF is a capture of a Canon body with a lens and GPS position
+func sampleTIFF(order binary.ByteOrder, latRef string, lonRef string, altRef uint16) []byte {
+
+	return buildTIFF(order,
+		[]tiffField{
+			asciiField(tagMake, "Canon"),
+			asciiField(tagModel, "Canon EOS R6"),
+			shortField(order, tagOrientation, 6),
+			asciiField(tagDateTime, "2024:05:01 10:20:31"),
+		},
+		[]tiffField{
+			rationalField(order, tagExposureTime, 1, 125),
+			rationalField(order, tagFNumber, 56, 10),
+			shortField(order, tagISO, 400),
+			asciiField(tagDateTimeOriginal, "2024:05:01 10:20:30"),
+			asciiField(tagOffsetTimeOrig, "+02:00"),
+			rationalField(order, tagFocalLength, 50, 1),
+			asciiField(tagBodySerialNumber, "012345678901"),
+			asciiField(tagLensModel, "RF50mm F1.8 STM"),
+		},
+		[]tiffField{
+			asciiField(tagGPSLatitudeRef, latRef),
+			rationalField(order, tagGPSLatitude, 52, 1, 30, 1, 36, 1),
+			asciiField(tagGPSLongitudeRef, lonRef),
+			rationalField(order, tagGPSLongitude, 13, 1, 24, 1, 0, 1),
+			{tag: tagGPSAltitudeRef, typ: 1, count: 1, data: []byte{byte(altRef)}},
+			rationalField(order, tagGPSAltitude, 345, 10),
+			rationalField(order, tagGPSTimeStamp, 8, 1, 20, 1, 30, 1),
+			asciiField(tagGPSDateStamp, "2024:05:01"),
+		},
+	)
+}
+
+// jpegWithEXIF wraps an EXIF block in the segments of a JPEG image
+func jpegWithEXIF(tiff []byte) []byte {
+
+	var out bytes.Buffer
+	out.Write([]byte{0xff, 0xd8})
+
+	jfif := []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	out.Write([]byte{0xff, 0xe0})
+	binary.Write(&out, binary.BigEndian, uint16(len(jfif)+2))
+	out.Write(jfif)
+
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+	out.Write([]byte{0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(app1)+2))
+	out.Write(app1)
+
+	out.Write([]byte{0xff, 0xda, 0x00, 0x02, 0xff, 0xd9})
+	return out.Bytes()
+}
+
+func TestParseEXIF(t *testing.T) {
+
+	little := sampleTIFF(binary.LittleEndian, "N", "E", 0)
+	big := sampleTIFF(binary.BigEndian, "N", "E", 0)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"little endian TIFF", little},
+		{"big endian TIFF", big},
+		{"APP1 EXIF block", append([]byte("Exif\x00\x00"), little...)},
+		{"JPEG image", jpegWithEXIF(big)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			exif, err := ParseEXIF(tt.data)
+			if err != nil {
+				t.Fatalf("ParseEXIF: %v", err)
+			}
+
+			if exif.Make != "Canon" || exif.Model != "Canon EOS R6" {
+				t.Errorf("make, model = %q, %q", exif.Make, exif.Model)
+			}
+			if exif.Orientation != 6 {
+				t.Errorf("orientation = %d, want 6", exif.Orientation)
+			}
+			want := time.Date(2024, 5, 1, 10, 20, 30, 0, time.FixedZone("", 2*3600))
+			if !exif.DateTime.Equal(want) {
+				t.Errorf("date = %v, want %v", exif.DateTime, want)
+			}
+			if exif.Shutter() != "1/125" || exif.FNumber != 5.6 || exif.ISO != 400 || exif.FocalLength != 50 {
+				t.Errorf("exposure = %s f/%g ISO %d %gmm", exif.Shutter(), exif.FNumber, exif.ISO, exif.FocalLength)
+			}
+			if exif.LensModel != "RF50mm F1.8 STM" || exif.SerialNumber != "012345678901" {
+				t.Errorf("lens, serial = %q, %q", exif.LensModel, exif.SerialNumber)
+			}
+			if exif.GPS == nil {
+				t.Fatal("no GPS position")
+			}
+			if math.Abs(exif.GPS.Latitude-52.51) > 1e-9 || math.Abs(exif.GPS.Longitude-13.4) > 1e-9 || exif.GPS.Altitude != 34.5 {
+				t.Errorf("GPS = %g, %g, %gm", exif.GPS.Latitude, exif.GPS.Longitude, exif.GPS.Altitude)
+			}
+			if want := time.Date(2024, 5, 1, 8, 20, 30, 0, time.UTC); !exif.GPS.Time.Equal(want) {
+				t.Errorf("GPS time = %v, want %v", exif.GPS.Time, want)
+			}
+		})
+	}
+}
+
+func TestParseEXIFGPSReferences(t *testing.T) {
+
+	tests := []struct {
+		latRef    string
+		lonRef    string
+		altRef    uint16
+		latitude  float64
+		longitude float64
+		altitude  float64
+	}{
+		{"N", "E", 0, 52.51, 13.4, 34.5},
+		{"S", "E", 0, -52.51, 13.4, 34.5},
+		{"N", "W", 0, 52.51, -13.4, 34.5},
+		{"S", "W", 1, -52.51, -13.4, -34.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.latRef+tt.lonRef, func(t *testing.T) {
+
+			exif, err := ParseEXIF(sampleTIFF(binary.LittleEndian, tt.latRef, tt.lonRef, tt.altRef))
+			if err != nil {
+				t.Fatalf("ParseEXIF: %v", err)
+			}
+			if exif.GPS == nil {
+				t.Fatal("no GPS position")
+			}
+			if math.Abs(exif.GPS.Latitude-tt.latitude) > 1e-9 || math.Abs(exif.GPS.Longitude-tt.longitude) > 1e-9 || exif.GPS.Altitude != tt.altitude {
+				t.Errorf("GPS = %g, %g, %gm, want %g, %g, %gm",
+					exif.GPS.Latitude, exif.GPS.Longitude, exif.GPS.Altitude, tt.latitude, tt.longitude, tt.altitude)
+			}
+		})
+	}
+}
+
+func TestParseEXIFInvalid(t *testing.T) {
+
+	order := binary.LittleEndian
+	valid := sampleTIFF(order, "N", "E", 0)
+
+	// IFD0 pointing past the end of the data
+	farIFD0 := append([]byte{}, valid...)
+	order.PutUint32(farIFD0[4:], uint32(len(valid)))
+
+	// IFD0 announcing more entries than the data holds
+	longIFD0 := append([]byte{}, valid...)
+	order.PutUint16(longIFD0[8:], 0xffff)
+
+	// EXIF IFD pointer out of range
+	farExif := buildTIFF(order, []tiffField{longField(order, tagExifIFD, 0xfffffff0)}, nil, nil)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"unknown format", []byte("GIF89a")},
+		{"truncated header", []byte("II*\x00")},
+		{"unknown byte order", []byte("Exif\x00\x00XX*\x00\x08\x00\x00\x00")},
+		{"IFD0 out of range", farIFD0},
+		{"IFD0 truncated", longIFD0},
+		{"IFD0 cut off", valid[:20]},
+		{"EXIF IFD out of range", farExif},
+		{"JPEG without EXIF", []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x04, 0x00, 0x00, 0xff, 0xda}},
+		{"JPEG segment past the end", []byte{0xff, 0xd8, 0xff, 0xe1, 0x7f, 0xff, 'E', 'x', 'i', 'f', 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseEXIF(tt.data); err == nil {
+				t.Error("ParseEXIF succeeded on invalid data")
+			}
+		})
+	}
+}
+
+func TestParseEXIFValueOutOfRange(t *testing.T) {
+
+	order := binary.BigEndian
+	data := buildTIFF(order, []tiffField{asciiField(tagMake, "Nikon Corporation"), asciiField(tagModel, "Z 6")}, nil, nil)
+
+	// the Make value lives after the directory, point it past the end
+	order.PutUint32(data[8+2+8:], uint32(len(data)))
+
+	exif, err := ParseEXIF(data)
+	if err != nil {
+		t.Fatalf("ParseEXIF: %v", err)
+	}
+	if exif.Make != "" || exif.Model != "Z 6" {
+		t.Errorf("make, model = %q, %q, want the broken make skipped", exif.Make, exif.Model)
+	}
+}
@@ -0,0 +1,123 @@
+package gogp2
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	Log "github.com/qazf88/golog"
+)
+
+// ExposureParameter is one of the three exposure settings of the camera
+type ExposureParameter string
+
+// exposure parameters
+const (
+	//ExposureShutter : shutter speed ("1/125", "0.5", "30")
+	ExposureShutter ExposureParameter = "shutterspeed"
+	//ExposureAperture : aperture ("f/5.6", "5.6")
+	ExposureAperture ExposureParameter = "aperture"
+	//ExposureISO : ISO sensitivity ("100", "3200")
+	ExposureISO ExposureParameter = "iso"
+)
+
+// widget names used by the different drivers for the exposure parameters
+var exposureWidgetNames = map[ExposureParameter][]string{
+	ExposureShutter:  {"shutterspeed", "shutterspeed2", "eos-shutterspeed"},
+	ExposureAperture: {"aperture", "f-number", "eos-aperture"},
+	ExposureISO:      {"iso", "isospeed", "eos-iso"},
+}
+
+// exposureWidgetName
+func (c *Camera) exposureWidgetName(param ExposureParameter) (string, error) {
+
+	names, ok := exposureWidgetNames[param]
+	if !ok {
+		err := fmt.Sprintf("unknown exposure parameter '%s'", param)
+		Log.Error(err)
+		return "", fmt.Errorf(err)
+	}
+
+	for _, name := range names {
+		if _, err := c.getGpWidgetByName(name); err == nil {
+			return name, nil
+		}
+	}
+
+	err := fmt.Sprintf("camera has no widget for exposure parameter '%s'", param)
+	Log.Error(err)
+	return "", fmt.Errorf(err)
+}
+
+// exposureValue converts a widget value to stops of light, a greater value means a brighter image
+func exposureValue(param ExposureParameter, value string) (float64, bool) {
+
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch param {
+	case ExposureShutter:
+		value = strings.TrimRight(value, "s\"")
+		seconds := 0.0
+		if i := strings.Index(value, "/"); i > 0 {
+			num, err := strconv.ParseFloat(value[:i], 64)
+			if err != nil {
+				return 0, false
+			}
+			den, err := strconv.ParseFloat(value[i+1:], 64)
+			if err != nil || den == 0 {
+				return 0, false
+			}
+			seconds = num / den
+		} else {
+			s, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0, false
+			}
+			seconds = s
+		}
+		if seconds <= 0 {
+			return 0, false
+		}
+		return math.Log2(seconds), true
+
+	case ExposureAperture:
+		value = strings.TrimPrefix(strings.TrimPrefix(value, "f"), "/")
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || number <= 0 {
+			return 0, false
+		}
+		return -2 * math.Log2(number), true
+
+	case ExposureISO:
+		iso, err := strconv.ParseFloat(value, 64)
+		if err != nil || iso <= 0 {
+			return 0, false
+		}
+		return math.Log2(iso), true
+	}
+
+	return 0, false
+}
+
+// nearestChoice returns the choice which exposure is closest to target
+func nearestChoice(param ExposureParameter, choices []string, target float64) (string, float64, bool) {
+
+	found := false
+	best := ""
+	bestEV := 0.0
+
+	for _, choice := range choices {
+		ev, ok := exposureValue(param, choice)
+		if !ok {
+			continue
+		}
+		if !found || math.Abs(ev-target) < math.Abs(bestEV-target) {
+			best = choice
+			bestEV = ev
+			found = true
+		}
+	}
+
+	return best, bestEV, found
+}
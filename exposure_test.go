@@ -0,0 +1,113 @@
+package gogp2
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestExposureValue(t *testing.T) {
+
+	tests := []struct {
+		param ExposureParameter
+		value string
+		want  float64
+		ok    bool
+	}{
+		{ExposureShutter, "1/125", -math.Log2(125), true},
+		{ExposureShutter, "30", math.Log2(30), true},
+		{ExposureShutter, " 0.5s ", -1, true},
+		{ExposureShutter, "2\"", 1, true},
+		{ExposureShutter, "1/0", 0, false},
+		{ExposureShutter, "bulb", 0, false},
+		{ExposureShutter, "0", 0, false},
+		{ExposureAperture, "f/5.6", -2 * math.Log2(5.6), true},
+		{ExposureAperture, "F8", -6, true},
+		{ExposureAperture, "4", -4, true},
+		{ExposureAperture, "implicit auto", 0, false},
+		{ExposureISO, "100", math.Log2(100), true},
+		{ExposureISO, "Auto", 0, false},
+		{ExposureParameter("whitebalance"), "5000", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.param)+" "+tt.value, func(t *testing.T) {
+			got, ok := exposureValue(tt.param, tt.value)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("exposureValue = %g, %v, want %g, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNearestChoice(t *testing.T) {
+
+	shutter := []string{"bulb", "1/250", "1/125", "1/60", "1/30"}
+
+	tests := []struct {
+		name    string
+		param   ExposureParameter
+		choices []string
+		target  float64
+		want    string
+		ok      bool
+	}{
+		{"exact", ExposureShutter, shutter, -math.Log2(125), "1/125", true},
+		{"between", ExposureShutter, shutter, -math.Log2(70), "1/60", true},
+		{"below range", ExposureShutter, shutter, -20, "1/250", true},
+		{"above range", ExposureShutter, shutter, 5, "1/30", true},
+		{"aperture", ExposureAperture, []string{"f/2.8", "f/4", "f/5.6"}, -4.2, "f/4", true},
+		{"no usable choice", ExposureISO, []string{"Auto"}, 7, "", false},
+		{"no choices", ExposureISO, nil, 7, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, ok := nearestChoice(tt.param, tt.choices, tt.target)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("nearestChoice = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBracketValues(t *testing.T) {
+
+	shutter := []string{"1/500", "1/250", "1/125", "1/60", "1/30"}
+	base := -math.Log2(125)
+
+	tests := []struct {
+		name  string
+		steps int
+		ev    float64
+		want  []string
+		err   error
+	}{
+		{"single", 1, 1, []string{"1/125"}, nil},
+		{"three frames", 3, 1, []string{"1/250", "1/125", "1/60"}, nil},
+		{"five frames", 5, 1, shutter, nil},
+		{"two stops", 3, 2, []string{"1/500", "1/125", "1/30"}, nil},
+		{"past the choices", 7, 1, nil, ErrBracketRange},
+		{"spacing below the choices", 3, 0.25, nil, ErrBracketRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			spec := BracketSpec{Steps: tt.steps, EVSpacing: tt.ev, Parameter: ExposureShutter}
+			values, _, err := bracketValues(spec, shutter, base)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("bracketValues error = %v, want %v", err, tt.err)
+			}
+			if len(values) != len(tt.want) {
+				t.Fatalf("bracketValues = %q, want %q", values, tt.want)
+			}
+			for i := range values {
+				if values[i] != tt.want[i] {
+					t.Errorf("bracketValues = %q, want %q", values, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -6,44 +6,381 @@ package gogp2
 // #include <stdlib.h>
 import "C"
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"reflect"
+	"os"
+	"path"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 	"unsafe"
 
 	Log "github.com/qazf88/golog"
 )
 
+// size of the chunks read by DownloadFileFrom
+const downloadChunkSize = 1 << 20
+
 // DownloadImage
 func (c *Camera) DownloadImage(buffer io.Writer, file *CameraFilePath, leaveOnCamera bool) error {
 
-	_file, err := newFile()
+	_file, err := c.getFile(file, FileTypeNormal)
 	if err != nil {
-		Log.Error(err.Error())
 		return err
 	}
 	defer C.gp_file_free(_file)
 
-	fileDir := C.CString(file.Folder)
+	err = getFileBytes(_file, buffer)
+	if err != nil {
+		if !leaveOnCamera {
+			fileDir := C.CString(file.Folder)
+			defer C.free(unsafe.Pointer(fileDir))
+
+			fileName := C.CString(file.Name)
+			defer C.free(unsafe.Pointer(fileName))
+
+			c.mu.Lock()
+			C.gp_camera_file_delete(c.Camera, fileDir, fileName, c.Context)
+			c.mu.Unlock()
+		}
+		Log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// DownloadFile writes the part of the file selected by fileType to buffer
+// FileTypePreview gives the small thumbnail generated by the camera, FileTypeExif the EXIF block only
+func (c *Camera) DownloadFile(ctx context.Context, path CameraFilePath, fileType int, buffer io.Writer) error {
+
+	_, err := c.DownloadFileFrom(ctx, path, fileType, 0, buffer)
+	return err
+}
+
+// DownloadFileFrom streams the file from offset in chunks, so memory use does not grow with the file size
+// returns the bytes written, an interrupted download resumes at offset plus written
+func (c *Camera) DownloadFileFrom(ctx context.Context, path CameraFilePath, fileType int, offset int64, buffer io.Writer) (int64, error) {
+
+	if fileType < FileTypePreview || fileType > FileTypeMetadata {
+		err := fmt.Sprintf("invalid file type: %d", fileType)
+		Log.Error(err)
+		return 0, fmt.Errorf(err)
+	}
+
+	if offset < 0 {
+		err := fmt.Sprintf("invalid offset: %d", offset)
+		Log.Error(err)
+		return 0, fmt.Errorf(err)
+	}
+
+	// the loop stops at the size reported by the camera, without it at the first short read
+	end := int64(-1)
+	if info, err := c.FileInfo(path); err == nil {
+		end = filePartSize(info, fileType)
+	}
+
+	chunk := make([]byte, downloadChunkSize)
+	written := int64(0)
+
+	for end < 0 || offset+written < end {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		want := chunk
+		if left := end - offset - written; end >= 0 && left < int64(len(chunk)) {
+			want = chunk[:left]
+		}
+
+		size, err := c.readFileAt(&path, fileType, offset+written, want)
+		if err == errPartialReadNotSupported && written == 0 {
+			return c.downloadWholeFile(&path, fileType, offset, buffer)
+		}
+		if err != nil {
+			return written, err
+		}
+
+		n, err := buffer.Write(want[:size])
+		written += int64(n)
+		if err != nil {
+			Log.Error(err.Error())
+			return written, err
+		}
+
+		if size < len(want) {
+			break
+		}
+	}
+
+	return written, nil
+}
+
+// filePartSize returns the size of the part of the file selected by fileType, -1 if the camera does not report it
+func filePartSize(info *FileInfo, fileType int) int64 {
+
+	size := int64(0)
+	switch fileType {
+	case FileTypeNormal, FileTypeRaw:
+		size = info.File.Size
+	case FileTypePreview:
+		size = info.Preview.Size
+	case FileTypeAudio:
+		size = info.Audio.Size
+	}
+
+	if size <= 0 {
+		return -1
+	}
+	return size
+}
+
+// errPartialReadNotSupported is returned by readFileAt for drivers which can only get whole files
+var errPartialReadNotSupported = errors.New("partial file read not supported")
+
+// readFileAt reads the file from offset into chunk and returns the bytes read, less than len(chunk) at the end of the file
+func (c *Camera) readFileAt(path *CameraFilePath, fileType int, offset int64, chunk []byte) (int, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(chunk) == 0 {
+		return 0, nil
+	}
+
+	fileDir := C.CString(path.Folder)
 	defer C.free(unsafe.Pointer(fileDir))
 
-	fileName := C.CString(file.Name)
+	fileName := C.CString(path.Name)
 	defer C.free(unsafe.Pointer(fileName))
 
-	res := C.gp_camera_file_get(c.Camera, fileDir, fileName, FileTypeNormal, _file, c.Context)
+	size := C.uint64_t(len(chunk))
+	res := C.gp_camera_file_read(c.Camera, fileDir, fileName, C.CameraFileType(fileType), C.uint64_t(offset), (*C.char)(unsafe.Pointer(&chunk[0])), &size, c.Context)
+	if res == C.GP_ERROR_NOT_SUPPORTED {
+		return 0, errPartialReadNotSupported
+	}
 	if res != OK {
-		_err := fmt.Sprintf("cannot download photo by name %s, error code: %d", C.GoString(fileName), res)
+		err := fmt.Sprintf("cannot read file by name %s at offset %d, error code: %d", path.Name, offset, res)
+		Log.Error(err)
+		return 0, fmt.Errorf(err)
+	}
+
+	return int(size), nil
+}
+
+// downloadWholeFile gets the whole file for drivers without partial reads and writes the part from offset to buffer
+// the file goes through a temporary file, so memory use does not grow with the file size
+func (c *Camera) downloadWholeFile(path *CameraFilePath, fileType int, offset int64, buffer io.Writer) (int64, error) {
+
+	tmp, err := os.CreateTemp("", "gogp2-*.part")
+	if err != nil {
+		Log.Error(err.Error())
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// gp_file_free closes the descriptor of the camera file
+	fd, err := syscall.Dup(int(tmp.Fd()))
+	if err != nil {
+		Log.Error(err.Error())
+		return 0, err
+	}
+
+	var _file *C.CameraFile
+	res := C.gp_file_new_from_fd(&_file, C.int(fd))
+	if res != OK {
+		syscall.Close(fd)
+		err := fmt.Sprintf("cannot create camera file, error code: %d", res)
+		Log.Error(err)
+		return 0, fmt.Errorf(err)
+	}
+
+	err = c.fileGet(path, fileType, _file)
+	C.gp_file_free(_file)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tmp.Seek(offset, io.SeekStart)
+	if err != nil {
+		Log.Error(err.Error())
+		return 0, err
+	}
+
+	n, err := io.Copy(buffer, tmp)
+	if err != nil {
+		Log.Error(err.Error())
+	}
+
+	return n, err
+}
+
+// getFile downloads the whole file into memory
+func (c *Camera) getFile(path *CameraFilePath, fileType int) (*C.CameraFile, error) {
+
+	_file, err := newFile()
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	err = c.fileGet(path, fileType, _file)
+	if err != nil {
+		C.gp_file_free(_file)
+		return nil, err
+	}
+
+	return _file, nil
+}
+
+// fileGet downloads the file into _file
+func (c *Camera) fileGet(path *CameraFilePath, fileType int, _file *C.CameraFile) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fileDir := C.CString(path.Folder)
+	defer C.free(unsafe.Pointer(fileDir))
+
+	fileName := C.CString(path.Name)
+	defer C.free(unsafe.Pointer(fileName))
+
+	res := C.gp_camera_file_get(c.Camera, fileDir, fileName, C.CameraFileType(fileType), _file, c.Context)
+	if res != OK {
+		_err := fmt.Sprintf("cannot download file by name %s, error code: %d", C.GoString(fileName), res)
 		Log.Error(_err)
 		return fmt.Errorf(_err)
 	}
 
-	err = getFileBytes(_file, buffer)
-	if err != nil && !leaveOnCamera {
-		C.gp_camera_file_delete(c.Camera, fileDir, fileName, c.Context)
+	return nil
+}
+
+// FileInfo returns the information the camera has about the file, its preview and audio
+func (c *Camera) FileInfo(path CameraFilePath) (*FileInfo, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fileDir := C.CString(path.Folder)
+	defer C.free(unsafe.Pointer(fileDir))
+
+	fileName := C.CString(path.Name)
+	defer C.free(unsafe.Pointer(fileName))
+
+	var info C.CameraFileInfo
+	res := C.gp_camera_file_get_info(c.Camera, fileDir, fileName, &info, c.Context)
+	if res != OK {
+		err := fmt.Sprintf("cannot get info of file by name %s, error code: %d", path.Name, res)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	fileInfo := &FileInfo{}
+
+	fileInfo.File = filePartInfo(info.file.fields, info.file.status, uint64(info.file.size), &info.file._type[0])
+	if info.file.fields&C.GP_FILE_INFO_WIDTH != 0 {
+		fileInfo.File.Width = int(info.file.width)
+	}
+	if info.file.fields&C.GP_FILE_INFO_HEIGHT != 0 {
+		fileInfo.File.Height = int(info.file.height)
+	}
+	if info.file.fields&C.GP_FILE_INFO_PERMISSIONS != 0 {
+		fileInfo.File.Readable = info.file.permissions&C.GP_FILE_PERM_READ != 0
+		fileInfo.File.Deletable = info.file.permissions&C.GP_FILE_PERM_DELETE != 0
+	}
+	if info.file.fields&C.GP_FILE_INFO_MTIME != 0 {
+		fileInfo.File.ModTime = time.Unix(int64(info.file.mtime), 0)
+	}
+
+	fileInfo.Preview = filePartInfo(info.preview.fields, info.preview.status, uint64(info.preview.size), &info.preview._type[0])
+	if info.preview.fields&C.GP_FILE_INFO_WIDTH != 0 {
+		fileInfo.Preview.Width = int(info.preview.width)
+	}
+	if info.preview.fields&C.GP_FILE_INFO_HEIGHT != 0 {
+		fileInfo.Preview.Height = int(info.preview.height)
+	}
+
+	fileInfo.Audio = filePartInfo(info.audio.fields, info.audio.status, uint64(info.audio.size), &info.audio._type[0])
+
+	return fileInfo, nil
+}
+
+// filePartInfo fills the fields common to the file, preview and audio info
+func filePartInfo(fields C.CameraFileInfoFields, status C.CameraFileStatus, size uint64, mime *C.char) FilePartInfo {
+
+	part := FilePartInfo{}
+
+	if fields&C.GP_FILE_INFO_SIZE != 0 {
+		part.Size = int64(size)
+	}
+	if fields&C.GP_FILE_INFO_TYPE != 0 {
+		part.Type = C.GoString(mime)
+	}
+	if fields&C.GP_FILE_INFO_STATUS != 0 {
+		part.Downloaded = status == C.GP_FILE_STATUS_DOWNLOADED
+	}
+
+	return part
+}
+
+// UploadFile writes the content of reader to folder/name on the camera
+func (c *Camera) UploadFile(ctx context.Context, folder string, name string, reader io.Reader, fileType int) error {
+
+	if fileType < FileTypePreview || fileType > FileTypeMetadata {
+		err := fmt.Sprintf("invalid file type: %d", fileType)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	_file, err := newFile()
+	if err != nil {
 		Log.Error(err.Error())
 		return err
 	}
+	defer C.gp_file_free(_file)
+
+	chunk := make([]byte, downloadChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := reader.Read(chunk)
+		if n > 0 {
+			res := C.gp_file_append(_file, (*C.char)(unsafe.Pointer(&chunk[0])), C.ulong(n))
+			if res != OK {
+				err := fmt.Sprintf("error append data to camera file, error code: %d", res)
+				Log.Error(err)
+				return fmt.Errorf(err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			Log.Error(err.Error())
+			return err
+		}
+	}
+
+	fileDir := C.CString(folder)
+	defer C.free(unsafe.Pointer(fileDir))
+
+	fileName := C.CString(name)
+	defer C.free(unsafe.Pointer(fileName))
+
+	c.mu.Lock()
+	res := C.gp_camera_folder_put_file(c.Camera, fileDir, fileName, C.CameraFileType(fileType), _file, c.Context)
+	c.mu.Unlock()
+	if res != OK {
+		err := fmt.Sprintf("cannot upload file by name %s to folder %s, error code: %d", name, folder, res)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
 
 	return nil
 }
@@ -70,33 +407,44 @@ func newFile() (*C.CameraFile, error) {
 // getFileBytes
 func getFileBytes(gpFileIn *C.CameraFile, bufferOut io.Writer) error {
 
+	data, err := fileData(gpFileIn)
+	if err != nil {
+		return err
+	}
+
+	_, err = bufferOut.Write(data)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// fileData returns the content of the camera file without copying it, valid until the file is freed
+func fileData(gpFileIn *C.CameraFile) ([]byte, error) {
+
 	var fileData *C.char
 	var fileLen C.ulong
 	res := C.gp_file_get_data_and_size(gpFileIn, (**C.char)(unsafe.Pointer(&fileData)), &fileLen)
 	if res != OK {
 		err := fmt.Sprintf("error get data and size from camera file: error code: %d", res)
 		Log.Error(err)
-		return fmt.Errorf(err)
+		return nil, fmt.Errorf(err)
 	}
 
-	hdr := reflect.SliceHeader{
-		Data: uintptr(unsafe.Pointer(fileData)),
-		Len:  int(fileLen),
-		Cap:  int(fileLen),
+	if fileData == nil || fileLen == 0 {
+		return []byte{}, nil
 	}
 
-	goSlice := *(*[]byte)(unsafe.Pointer(&hdr))
-	_, err := bufferOut.Write(goSlice)
-	if err != nil {
-		Log.Error(err.Error())
-		return err
-	}
-	return nil
+	return unsafe.Slice((*byte)(unsafe.Pointer(fileData)), int(fileLen)), nil
 }
 
 // DeleteFile
 func (c *Camera) DeleteFile(path *CameraFilePath) error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	fileDir := C.CString(path.Folder)
 	defer C.free(unsafe.Pointer(fileDir))
 
@@ -115,6 +463,9 @@ func (c *Camera) DeleteFile(path *CameraFilePath) error {
 
 // ListFolders
 func (c *Camera) ListFolders(folder string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if folder == "" {
 		folder = "/"
 	}
@@ -126,7 +477,12 @@ func (c *Camera) ListFolders(folder string) ([]string, error) {
 	C_folder := C.CString(folder)
 	defer C.free(unsafe.Pointer(C_folder))
 
-	C.gp_camera_folder_list_folders(c.Camera, C_folder, cameraList, c.Context)
+	res := C.gp_camera_folder_list_folders(c.Camera, C_folder, cameraList, c.Context)
+	if res != OK {
+		err := fmt.Sprintf("cannot list folders in %s, error code: %d", folder, res)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
 
 	folderMap, _ := cameraListToMap(cameraList)
 
@@ -136,6 +492,7 @@ func (c *Camera) ListFolders(folder string) ([]string, error) {
 		names[i] = key
 		i += 1
 	}
+	sort.Strings(names)
 
 	return names, nil
 }
@@ -159,6 +516,9 @@ func (c *Camera) RecursiveListFolders(folder string) []string {
 
 // ListFiles
 func (c *Camera) ListFiles(folder string) ([]string, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if folder == "" {
 		folder = "/"
 	}
@@ -183,6 +543,7 @@ func (c *Camera) ListFiles(folder string) ([]string, int) {
 		names[i] = key
 		i += 1
 	}
+	sort.Strings(names)
 
 	return names, int(err)
 }
@@ -215,3 +576,126 @@ func cameraListToMap(cameraList *C.CameraList) (map[string]string, int) {
 
 	return vals, 0
 }
+
+// MakeDir creates the folder name in folder
+func (c *Camera) MakeDir(folder string, name string) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fileDir := C.CString(folder)
+	defer C.free(unsafe.Pointer(fileDir))
+
+	dirName := C.CString(name)
+	defer C.free(unsafe.Pointer(dirName))
+
+	res := C.gp_camera_folder_make_dir(c.Camera, fileDir, dirName, c.Context)
+	if res != OK {
+		err := fmt.Sprintf("cannot create folder %s in %s, error code: %d", name, folder, res)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	return nil
+}
+
+// RemoveDir removes the empty folder name from folder
+func (c *Camera) RemoveDir(folder string, name string) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fileDir := C.CString(folder)
+	defer C.free(unsafe.Pointer(fileDir))
+
+	dirName := C.CString(name)
+	defer C.free(unsafe.Pointer(dirName))
+
+	res := C.gp_camera_folder_remove_dir(c.Camera, fileDir, dirName, c.Context)
+	if res != OK {
+		err := fmt.Sprintf("cannot remove folder %s from %s, error code: %d", name, folder, res)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	return nil
+}
+
+// DeleteAll deletes all files in folder, subfolders are left alone
+func (c *Camera) DeleteAll(folder string) error {
+
+	fileDir := C.CString(folder)
+	defer C.free(unsafe.Pointer(fileDir))
+
+	c.mu.Lock()
+	res := C.gp_camera_folder_delete_all(c.Camera, fileDir, c.Context)
+	c.mu.Unlock()
+	if res == OK {
+		return nil
+	}
+
+	if res != C.GP_ERROR_NOT_SUPPORTED {
+		err := fmt.Sprintf("cannot delete files in %s, error code: %d", folder, res)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	// the driver deletes files only one by one
+	names, _res := c.ListFiles(folder)
+	if _res != OK {
+		err := fmt.Sprintf("cannot list files in %s, error code: %d", folder, _res)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	for _, name := range names {
+		err := c.DeleteFile(&CameraFilePath{Name: name, Folder: folder})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveDirAll removes folder with all its files and subfolders
+func (c *Camera) RemoveDirAll(folder string) error {
+
+	folder = path.Clean("/" + folder)
+	if folder == "/" {
+		err := "cannot remove the root folder"
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	// Walk reports a listing error instead of leaving the folder out
+	folders := []string{}
+	err := c.Walk(context.Background(), folder, func(file CameraFilePath, err error) error {
+		if err != nil {
+			return err
+		}
+		if file.Isdir {
+			folders = append(folders, joinPath(file))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// subfolders come after their parent, so the reverse order empties children first
+	for i := len(folders) - 1; i >= 0; i-- {
+		err := c.DeleteAll(folders[i])
+		if err != nil {
+			return err
+		}
+
+		parent, name := path.Split(folders[i])
+		err = c.RemoveDir(path.Clean(parent), name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// ErrNotSupported is returned when the camera lacks the requested feature
+var ErrNotSupported = errors.New("not supported by camera")
+
+// focus widgets shared by the Canon and Nikon drivers
+const (
+	focusDriveWidget  = "manualfocusdrive"
+	autofocusWidget   = "autofocusdrive"
+	cancelFocusWidget = "cancelautofocus"
+)
+
+// widgets reporting the focus mode
+var focusModeWidgets = []string{"focusmode", "focusmode2", "afmode"}
+
+// time given to the lens to finish a focus movement
+const focusSettleTime = 200 * time.Millisecond
+
+// FocusFrame is a single frame of a focus stack
+type FocusFrame struct {
+	Position int    // focus position relative to the first frame
+	Data     []byte // downloaded image
+}
+
+// FocusStatus describes the focus abilities of the camera
+type FocusStatus struct {
+	Mode        string `json:"mode"`        // current focus mode, empty if the camera does not report it
+	Autofocus   bool   `json:"autofocus"`   // autofocus can be triggered with Focus
+	ManualDrive bool   `json:"manualDrive"` // focus can be moved with FocusNear and FocusFar
+}
+
+// focusWrite sends a focus widget value, tests replace it to record the writes
+var focusWrite = (*Camera).forceValue
+
+// Focus triggers the autofocus
+func (c *Camera) Focus() error {
+
+	// the widget keeps "1" from the last call, the write is forced to trigger it again
+	// writing 0 cancels the autofocus on Canon, see CancelFocus
+	err := focusWrite(c, autofocusWidget, "1")
+	if errors.Is(err, errWidgetNotFound) {
+		return ErrNotSupported
+	}
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// CancelFocus stops a running autofocus
+func (c *Camera) CancelFocus() error {
+
+	err := focusWrite(c, cancelFocusWidget, "1")
+	if errors.Is(err, errWidgetNotFound) {
+		err = focusWrite(c, autofocusWidget, "0")
+	}
+	if errors.Is(err, errWidgetNotFound) {
+		return ErrNotSupported
+	}
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// FocusNear moves the focus n steps towards the closest distance
+func (c *Camera) FocusNear(n int) error {
+	return c.driveFocus(-n)
+}
+
+// FocusFar moves the focus n steps towards infinity
+func (c *Camera) FocusFar(n int) error {
+	return c.driveFocus(n)
+}
+
+// FocusStatus
+func (c *Camera) FocusStatus() (FocusStatus, error) {
+
+	status := FocusStatus{}
+
+	if _, err := c.getGpWidgetByName(autofocusWidget); err == nil {
+		status.Autofocus = true
+	}
+
+	if _, err := c.getGpWidgetByName(focusDriveWidget); err == nil {
+		status.ManualDrive = true
+	}
+
+	for _, name := range focusModeWidgets {
+		if _, err := c.getGpWidgetByName(name); err != nil {
+			continue
+		}
+		mode, err := c.GetWidgetValueByName(name)
+		if err == nil {
+			status.Mode = mode
+			break
+		}
+	}
+
+	if !status.Autofocus && !status.ManualDrive && status.Mode == "" {
+		return status, ErrNotSupported
+	}
+
+	return status, nil
+}
+
+// FocusStack captures steps frames, moving the focus stepSize units towards infinity between them
+// negative stepSize moves the focus towards the closest distance
+func (c *Camera) FocusStack(ctx context.Context, steps int, stepSize int) ([]FocusFrame, error) {
+
+	if steps < 1 {
+		err := fmt.Sprintf("invalid focus stack steps: %d", steps)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	err := c.startLiveView()
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+	defer c.stopLiveView()
+
+	frames := []FocusFrame{}
+	for i := 0; i < steps; i++ {
+
+		if err := ctx.Err(); err != nil {
+			return frames, err
+		}
+
+		if i > 0 {
+			err := c.driveFocus(stepSize)
+			if err != nil {
+				return frames, err
+			}
+		}
+
+		var buffer bytes.Buffer
+		err := c.CapturePhoto(&buffer)
+		if err != nil {
+			return frames, err
+		}
+
+		frames = append(frames, FocusFrame{
+			Position: i * stepSize,
+			Data:     buffer.Bytes(),
+		})
+	}
+
+	return frames, nil
+}
+
+// driveFocus moves the focus by steps units, positive towards infinity, negative towards the closest distance
+func (c *Camera) driveFocus(steps int) error {
+
+	if steps == 0 {
+		return nil
+	}
+
+	_widget, err := c.getWidgetByName(focusDriveWidget)
+	if errors.Is(err, errWidgetNotFound) {
+		return ErrNotSupported
+	}
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	name := focusDriveWidget
+	switch _widget.Type {
+	case WidgetRange:
+		// Nikon: the value is the number of motor steps, forced as the same step size is written again and again
+		err = c.forceValue(name, strconv.Itoa(steps))
+		if err != nil {
+			Log.Error(err.Error())
+			return err
+		}
+		time.Sleep(focusSettleTime)
+
+	case WidgetRadio, WidgetMenu:
+		// Canon: "Near 1".."Near 3", "None", "Far 1".."Far 3", one smallest step per write
+		value := "Far 1"
+		if steps < 0 {
+			value = "Near 1"
+			steps = -steps
+		}
+		for i := 0; i < steps; i++ {
+			err = c.forceValue(name, value)
+			if err != nil {
+				Log.Error(err.Error())
+				return err
+			}
+			time.Sleep(focusSettleTime)
+			err = c.forceValue(name, "None")
+			if err != nil {
+				Log.Error(err.Error())
+				return err
+			}
+		}
+
+	default:
+		return ErrNotSupported
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package gogp2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// recordFocusWrites replaces the widget writes of the focus functions, widgets in missing do not exist
+func recordFocusWrites(t *testing.T, fail error, missing ...string) *[]string {
+
+	writes := []string{}
+	write := focusWrite
+	t.Cleanup(func() { focusWrite = write })
+
+	focusWrite = func(c *Camera, name string, value string) error {
+		for _, m := range missing {
+			if m == name {
+				return fmt.Errorf("could not retrieve widget by name '%s': %w", name, errWidgetNotFound)
+			}
+		}
+		if fail != nil {
+			return fail
+		}
+		writes = append(writes, name+"="+value)
+		return nil
+	}
+
+	return &writes
+}
+
+func TestFocusWrites(t *testing.T) {
+
+	cameraError := errors.New("error save widget, error code: -7")
+
+	tests := []struct {
+		name    string
+		call    func(c *Camera) error
+		fail    error
+		missing []string
+		writes  []string
+		err     error
+	}{
+		{"focus", (*Camera).Focus, nil, nil, []string{"autofocusdrive=1"}, nil},
+		{"focus without autofocus", (*Camera).Focus, nil, []string{autofocusWidget}, []string{}, ErrNotSupported},
+		{"focus camera error", (*Camera).Focus, cameraError, nil, []string{}, cameraError},
+		{"cancel", (*Camera).CancelFocus, nil, nil, []string{"cancelautofocus=1"}, nil},
+		{"cancel through autofocus", (*Camera).CancelFocus, nil, []string{cancelFocusWidget}, []string{"autofocusdrive=0"}, nil},
+		{"cancel without widgets", (*Camera).CancelFocus, nil, []string{cancelFocusWidget, autofocusWidget}, []string{}, ErrNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			writes := recordFocusWrites(t, tt.fail, tt.missing...)
+			c := &Camera{}
+
+			// every call reaches the camera, the cached widget value must not swallow the second one
+			want := []string{}
+			for i := 0; i < 2; i++ {
+				if err := tt.call(c); !errors.Is(err, tt.err) {
+					t.Fatalf("call %d: error = %v, want %v", i, err, tt.err)
+				}
+				want = append(want, tt.writes...)
+			}
+
+			if !reflect.DeepEqual(*writes, want) {
+				t.Errorf("writes = %v, want %v", *writes, want)
+			}
+		})
+	}
+}
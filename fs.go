@@ -0,0 +1,362 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+)
+
+// errSizeUnknown is returned when seeking from the end of a file the camera reports no size for
+var errSizeUnknown = errors.New("file size unknown")
+
+// CameraFS exposes the camera storage as a read-only fs.FS
+// names are slash separated and relative to the camera root, like "store_00010001/DCIM"
+// every folder is listed once, files added to the camera later show up in a new CameraFS
+type CameraFS struct {
+	camera *Camera
+	ctx    context.Context
+	mu     sync.Mutex
+	dirs   map[string][]CameraFilePath // folder listings by camera path
+}
+
+// FS returns the camera storage as fs.FS, ctx bounds every operation on it
+func (c *Camera) FS(ctx context.Context) *CameraFS {
+	return &CameraFS{camera: c, ctx: ctx, dirs: make(map[string][]CameraFilePath)}
+}
+
+// Open
+func (f *CameraFS) Open(name string) (fs.File, error) {
+
+	info, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &cameraDir{fs: f, info: info, name: name}, nil
+	}
+
+	return &cameraFile{fs: f, info: info}, nil
+}
+
+// Stat
+func (f *CameraFS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+// ReadDir
+func (f *CameraFS) ReadDir(name string) ([]fs.DirEntry, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := f.ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	entries, err := f.listDir(cameraPath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	dirEntries := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		if err := f.ctx.Err(); err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		info := &cameraFileInfo{path: entry}
+		if !entry.Isdir {
+			info.info, err = f.camera.FileInfo(entry)
+			if err != nil {
+				return nil, &fs.PathError{Op: "readdir", Path: path.Join(name, entry.Name), Err: err}
+			}
+		}
+		dirEntries = append(dirEntries, fs.FileInfoToDirEntry(info))
+	}
+
+	return dirEntries, nil
+}
+
+// listDir returns the entries of the camera folder dir, listing it on the first call only
+func (f *CameraFS) listDir(dir string) ([]CameraFilePath, error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if entries, ok := f.dirs[dir]; ok {
+		return entries, nil
+	}
+
+	entries, err := f.camera.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	f.dirs[dir] = entries
+
+	return entries, nil
+}
+
+// stat
+func (f *CameraFS) stat(op string, name string) (*cameraFileInfo, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := f.ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	if name == "." {
+		return &cameraFileInfo{path: folderPath("/")}, nil
+	}
+
+	// the camera has no stat, the parent folder tells whether name is a file or a folder
+	dir, base := path.Split(cameraPath(name))
+	entries, err := f.listDir(path.Clean(dir))
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	for _, entry := range entries {
+		if entry.Name != base {
+			continue
+		}
+		info := &cameraFileInfo{path: entry}
+		if !entry.Isdir {
+			info.info, err = f.camera.FileInfo(entry)
+			if err != nil {
+				return nil, &fs.PathError{Op: op, Path: name, Err: err}
+			}
+		}
+		return info, nil
+	}
+
+	return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// cameraPath converts a fs.FS name to a camera path
+func cameraPath(name string) string {
+	if name == "." {
+		return "/"
+	}
+	return "/" + name
+}
+
+// cameraFileInfo implements fs.FileInfo
+type cameraFileInfo struct {
+	path CameraFilePath
+	info *FileInfo
+}
+
+func (i *cameraFileInfo) Name() string {
+	if i.path.Name == "" {
+		return "."
+	}
+	return i.path.Name
+}
+
+func (i *cameraFileInfo) Size() int64 {
+	if i.info == nil {
+		return 0
+	}
+	return i.info.File.Size
+}
+
+func (i *cameraFileInfo) Mode() fs.FileMode {
+	if i.path.Isdir {
+		return fs.ModeDir | 0555
+	}
+	if i.info != nil && i.info.File.Deletable {
+		return 0644
+	}
+	return 0444
+}
+
+func (i *cameraFileInfo) ModTime() time.Time {
+	if i.info == nil {
+		return time.Time{}
+	}
+	return i.info.File.ModTime
+}
+
+func (i *cameraFileInfo) IsDir() bool {
+	return i.path.Isdir
+}
+
+func (i *cameraFileInfo) Sys() interface{} {
+	return i.path
+}
+
+// cameraFile implements fs.File, io.Seeker and io.ReaderAt, reading the content from the camera chunk by chunk
+type cameraFile struct {
+	fs     *CameraFS
+	info   *cameraFileInfo
+	offset int64
+	whole  *bytes.Reader // whole content for drivers without partial reads
+}
+
+func (f *cameraFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *cameraFile) Read(p []byte) (int, error) {
+
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+
+	return n, err
+}
+
+func (f *cameraFile) ReadAt(p []byte, off int64) (int, error) {
+
+	if err := f.fs.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: fs.ErrInvalid}
+	}
+
+	if f.whole != nil {
+		return f.whole.ReadAt(p, off)
+	}
+
+	// reads stop at the size the camera reports, some drivers fail reading at the end of the file
+	atEnd := false
+	if size := f.info.Size(); size > 0 {
+		if off >= size {
+			return 0, io.EOF
+		}
+		if left := size - off; left < int64(len(p)) {
+			p = p[:left]
+			atEnd = true
+		}
+	}
+
+	read := 0
+	for read < len(p) {
+		n, err := f.fs.camera.readFileAt(&f.info.path, FileTypeNormal, off+int64(read), p[read:])
+		if err == errPartialReadNotSupported {
+			var buffer bytes.Buffer
+			_, err = f.fs.camera.downloadWholeFile(&f.info.path, FileTypeNormal, 0, &buffer)
+			if err != nil {
+				return read, err
+			}
+			f.whole = bytes.NewReader(buffer.Bytes())
+			return f.whole.ReadAt(p, off)
+		}
+		if err != nil {
+			return read, err
+		}
+		if n == 0 {
+			break
+		}
+		read += n
+	}
+
+	if read < len(p) || atEnd {
+		return read, io.EOF
+	}
+
+	return read, nil
+}
+
+func (f *cameraFile) Seek(offset int64, whence int) (int64, error) {
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size := f.info.Size()
+		if f.whole != nil {
+			size = f.whole.Size()
+		} else if size == 0 {
+			return f.offset, &fs.PathError{Op: "seek", Path: f.info.Name(), Err: errSizeUnknown}
+		}
+		offset += size
+	default:
+		return f.offset, &fs.PathError{Op: "seek", Path: f.info.Name(), Err: fs.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return f.offset, &fs.PathError{Op: "seek", Path: f.info.Name(), Err: fs.ErrInvalid}
+	}
+
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *cameraFile) Close() error {
+	return nil
+}
+
+// cameraDir implements fs.ReadDirFile
+type cameraDir struct {
+	fs      *CameraFS
+	info    *cameraFileInfo
+	name    string
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *cameraDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *cameraDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *cameraDir) Close() error {
+	return nil
+}
+
+func (d *cameraDir) ReadDir(n int) ([]fs.DirEntry, error) {
+
+	if !d.read {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+
+	return entries, nil
+}
+
+// interface checks
+var (
+	_ fs.ReadDirFS   = (*CameraFS)(nil)
+	_ fs.StatFS      = (*CameraFS)(nil)
+	_ fs.ReadDirFile = (*cameraDir)(nil)
+	_ io.ReadSeeker  = (*cameraFile)(nil)
+	_ io.ReaderAt    = (*cameraFile)(nil)
+)
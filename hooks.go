@@ -0,0 +1,79 @@
+package gogp2
+
+import (
+	"bytes"
+)
+
+// Capture is a photo passing through the capture middleware
+type Capture struct {
+	Path   CameraFilePath // file on the camera, set once the photo is taken
+	Buffer *bytes.Buffer  // downloaded image
+}
+
+// CaptureHandler takes a photo
+type CaptureHandler func(c *Camera, capture *Capture) error
+
+// CaptureMiddleware wraps a CaptureHandler, like an HTTP middleware
+type CaptureMiddleware func(next CaptureHandler) CaptureHandler
+
+// Use registers middleware for CapturePhoto, the first registered runs outermost
+// Bracket, FocusStack, Timelapse and PhotoBooth run through it, Burst does not as it holds the release in continuous drive
+// Use is safe while captures run, a capture already started keeps the middleware it started with
+func (c *Camera) Use(middleware ...CaptureMiddleware) {
+
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+
+	// copy on write, CapturePhoto ranges over the slice without holding the lock
+	chain := make([]CaptureMiddleware, 0, len(c.middleware)+len(middleware))
+	chain = append(chain, c.middleware...)
+	c.middleware = append(chain, middleware...)
+}
+
+// captureMiddleware returns the registered middleware
+func (c *Camera) captureMiddleware() []CaptureMiddleware {
+
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+
+	return c.middleware
+}
+
+// BeforeCapture runs fn before the photo is taken, an error cancels the capture
+func BeforeCapture(fn func(c *Camera) error) CaptureMiddleware {
+	return func(next CaptureHandler) CaptureHandler {
+		return func(c *Camera, capture *Capture) error {
+			err := fn(c)
+			if err != nil {
+				return err
+			}
+			return next(c, capture)
+		}
+	}
+}
+
+// AfterCapture runs fn after a successful capture
+func AfterCapture(fn func(c *Camera, capture *Capture) error) CaptureMiddleware {
+	return func(next CaptureHandler) CaptureHandler {
+		return func(c *Camera, capture *Capture) error {
+			err := next(c, capture)
+			if err != nil {
+				return err
+			}
+			return fn(c, capture)
+		}
+	}
+}
+
+// OnCaptureError runs fn when the capture failed, the error returned by fn replaces the original one
+func OnCaptureError(fn func(c *Camera, err error) error) CaptureMiddleware {
+	return func(next CaptureHandler) CaptureHandler {
+		return func(c *Camera, capture *Capture) error {
+			err := next(c, capture)
+			if err != nil {
+				return fn(c, err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package gogp2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// default import layout
+const (
+	importTemplate = "{date:2006/01/02}/{name}.{ext}"
+	importManifest = "import-manifest.json"
+)
+
+// placeholders of the import template, {name} or {name:format}
+var templatePlaceholder = regexp.MustCompile(`\{([a-z]+)(?::([^}]*))?\}`)
+
+// ImportOptions
+// Template placeholders: {date:2006/01/02} capture date in Go time layout, {model} camera model,
+// {seq:0000} sequence number padded to the width of the format, {name} original name without extension,
+// {ext} lower case extension
+type ImportOptions struct {
+	Root     string                                         // camera folder to import, "/" by default
+	Dest     string                                         // local archive folder
+	Template string                                         // path of the imported files below Dest
+	Manifest string                                         // Dest/import-manifest.json by default
+	Filter   func(file CameraFilePath, info *FileInfo) bool // files for which Filter returns false are ignored, nil imports all
+}
+
+// ImportedFile is an entry of the import manifest
+type ImportedFile struct {
+	Source   string    `json:"source"` // path on the camera
+	Dest     string    `json:"dest"`   // path relative to the archive folder
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"` // file time on the camera
+	SHA256   string    `json:"sha256"`
+	Date     time.Time `json:"date"`
+	Sequence int       `json:"sequence"`
+	Imported time.Time `json:"imported"`
+}
+
+// ImportManifest lists every file ever imported to an archive folder
+type ImportManifest struct {
+	Files []ImportedFile `json:"files"`
+}
+
+// Import downloads the files of the camera into the archive, renamed by the template
+// RAW and JPEG files with the same name share date, sequence number and collision suffix
+// files imported before are skipped by camera path, size and time without a download, other files already archived by content
+func (c *Camera) Import(ctx context.Context, options ImportOptions) (*ImportManifest, error) {
+
+	if options.Dest == "" {
+		err := "import destination is empty"
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+	if options.Root == "" {
+		options.Root = "/"
+	}
+	if options.Template == "" {
+		options.Template = importTemplate
+	}
+	if options.Manifest == "" {
+		options.Manifest = filepath.Join(options.Dest, importManifest)
+	}
+
+	manifest, err := loadImportManifest(options.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := map[string]string{}
+	known := map[string]ImportedFile{}
+	sequence := 0
+	for _, file := range manifest.Files {
+		if file.Dest != "" {
+			hashes[file.SHA256] = file.Dest
+		}
+		known[file.Source] = file
+		if file.Sequence > sequence {
+			sequence = file.Sequence
+		}
+	}
+
+	model, err := c.Model()
+	if err != nil {
+		Log.Warning(err.Error())
+		model = "camera"
+	}
+
+	groups := [][]importCandidate{}
+	groupIndex := map[string]int{}
+
+	err = c.Walk(ctx, options.Root, func(file CameraFilePath, err error) error {
+		if err != nil {
+			return err
+		}
+		if file.Isdir {
+			return nil
+		}
+		info, err := c.FileInfo(file)
+		if err != nil {
+			return err
+		}
+		if options.Filter != nil && !options.Filter(file, info) {
+			return nil
+		}
+
+		// RAW+JPEG pairs share folder and name
+		key := path.Join(file.Folder, strings.ToLower(strings.TrimSuffix(file.Name, path.Ext(file.Name))))
+		i, ok := groupIndex[key]
+		if !ok {
+			i = len(groups)
+			groupIndex[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], importCandidate{path: file, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+
+		if err := ctx.Err(); err != nil {
+			return manifest, err
+		}
+
+		groupSequence := sequence + 1
+		var groupDate time.Time
+		pending := []importCandidate{}
+		for _, file := range group {
+			entry, ok := known[joinPath(file.path)]
+			if ok && entry.Size == file.info.File.Size && entry.ModTime.Equal(file.info.File.ModTime) {
+				// the rest of the group joins the files imported by an earlier run
+				groupSequence, groupDate = entry.Sequence, entry.Date
+				continue
+			}
+			pending = append(pending, file)
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		paths := []CameraFilePath{}
+		for _, file := range group {
+			paths = append(paths, file.path)
+		}
+		date, groupModel := c.captureInfo(ctx, paths, group[0].info)
+		if groupModel == "" {
+			groupModel = model
+		}
+		if !groupDate.IsZero() {
+			date = groupDate
+		}
+
+		for i := range pending {
+			pending[i].dest = renderTemplate(options.Template, pending[i].path.Name, groupModel, date, groupSequence)
+		}
+
+		imported, err := c.importGroup(ctx, pending, options, hashes)
+		if err != nil {
+			return manifest, err
+		}
+		if len(imported) == 0 {
+			continue
+		}
+		if groupSequence > sequence {
+			sequence = groupSequence
+		}
+
+		for i := range imported {
+			imported[i].Date = date
+			imported[i].Sequence = groupSequence
+			known[imported[i].Source] = imported[i]
+		}
+		manifest.Files = append(manifest.Files, imported...)
+		err = saveImportManifest(options.Manifest, manifest)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// importCandidate is a file of a capture group
+type importCandidate struct {
+	path CameraFilePath
+	info *FileInfo
+	dest string // rendered template, before the collision suffix
+}
+
+// captureInfo returns capture date and camera model from the EXIF data of the first of files which has it,
+// the file time of info and an empty model without it
+func (c *Camera) captureInfo(ctx context.Context, files []CameraFilePath, info *FileInfo) (time.Time, string) {
+
+	for _, file := range files {
+		if exif, err := c.EXIF(ctx, file); err == nil && !exif.DateTime.IsZero() {
+			return exif.DateTime, exif.Model
+		}
+	}
+
+	if !info.File.ModTime.IsZero() {
+		return info.File.ModTime, ""
+	}
+
+	return time.Now(), ""
+}
+
+// importGroup downloads the files of a capture group and moves those not archived yet below the archive folder
+// all files of the group get the same collision suffix
+func (c *Camera) importGroup(ctx context.Context, files []importCandidate, options ImportOptions, hashes map[string]string) ([]ImportedFile, error) {
+
+	err := os.MkdirAll(options.Dest, 0755)
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	tmps := []string{}
+	defer func() {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}()
+
+	imported := []ImportedFile{}
+	dests := []string{}
+	for _, file := range files {
+
+		tmp, err := os.CreateTemp(options.Dest, ".import.*.part")
+		if err != nil {
+			Log.Error(err.Error())
+			return nil, err
+		}
+		tmps = append(tmps, tmp.Name())
+
+		hash := sha256.New()
+		size, err := c.DownloadFileFrom(ctx, file.path, FileTypeNormal, 0, io.MultiWriter(tmp, hash))
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			Log.Error(err.Error())
+			return nil, err
+		}
+
+		entry := ImportedFile{
+			Source:   joinPath(file.path),
+			Size:     size,
+			ModTime:  file.info.File.ModTime,
+			SHA256:   hex.EncodeToString(hash.Sum(nil)),
+			Imported: time.Now(),
+		}
+
+		if existing, ok := hashes[entry.SHA256]; ok {
+			Log.Info(fmt.Sprintf("%s is a duplicate of %s, skipped", entry.Source, existing))
+			tmps = tmps[:len(tmps)-1]
+			os.Remove(tmp.Name())
+			continue
+		}
+		for i, dest := range dests {
+			if dest == file.dest {
+				err := fmt.Sprintf("template %s gives %s and %s the same name", options.Template, imported[i].Source, entry.Source)
+				Log.Error(err)
+				return nil, fmt.Errorf(err)
+			}
+		}
+		// the same content twice in one group is archived once
+		hashes[entry.SHA256] = file.dest
+
+		imported = append(imported, entry)
+		dests = append(dests, file.dest)
+	}
+
+	suffix := groupSuffix(options.Dest, dests)
+	for i := range imported {
+
+		dest := withSuffix(dests[i], suffix)
+		local := filepath.Join(options.Dest, filepath.FromSlash(dest))
+
+		err = os.MkdirAll(filepath.Dir(local), 0755)
+		if err == nil {
+			err = os.Rename(tmps[i], local)
+		}
+		if err != nil {
+			Log.Error(err.Error())
+			return nil, err
+		}
+
+		imported[i].Dest = dest
+		hashes[imported[i].SHA256] = dest
+	}
+
+	return imported, nil
+}
+
+// renderTemplate
+func renderTemplate(template string, name string, model string, date time.Time, sequence int) string {
+
+	ext := path.Ext(name)
+
+	return templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		match := templatePlaceholder.FindStringSubmatch(placeholder)
+		switch match[1] {
+		case "date":
+			layout := match[2]
+			if layout == "" {
+				layout = "2006-01-02"
+			}
+			return date.Format(layout)
+		case "model":
+			return strings.ReplaceAll(strings.TrimSpace(model), " ", "_")
+		case "seq":
+			return fmt.Sprintf("%0*d", len(match[2]), sequence)
+		case "name":
+			return strings.TrimSuffix(name, ext)
+		case "ext":
+			return strings.ToLower(strings.TrimPrefix(ext, "."))
+		}
+		return placeholder
+	})
+}
+
+// groupSuffix returns the first suffix, none, "_1", "_2"..., with which no name of dests exists in dir yet
+func groupSuffix(dir string, dests []string) string {
+
+	for i := 0; ; i++ {
+		suffix := ""
+		if i > 0 {
+			suffix = "_" + strconv.Itoa(i)
+		}
+
+		free := true
+		for _, dest := range dests {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(withSuffix(dest, suffix)))); !os.IsNotExist(err) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return suffix
+		}
+	}
+}
+
+// withSuffix inserts suffix before the extension of dest
+func withSuffix(dest string, suffix string) string {
+
+	ext := path.Ext(dest)
+	return strings.TrimSuffix(dest, ext) + suffix + ext
+}
+
+// loadImportManifest
+func loadImportManifest(name string) (*ImportManifest, error) {
+
+	manifest := &ImportManifest{Files: []ImportedFile{}}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		err := fmt.Sprintf("invalid import manifest %s: %s", name, err.Error())
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	return manifest, nil
+}
+
+// saveImportManifest
+func saveImportManifest(name string, manifest *ImportManifest) error {
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	tmp := name + ".part"
+	err = os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
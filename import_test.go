@@ -0,0 +1,93 @@
+package gogp2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenderTemplate(t *testing.T) {
+
+	date := time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC)
+
+	tests := []struct {
+		template string
+		name     string
+		model    string
+		sequence int
+		want     string
+	}{
+		{importTemplate, "IMG_0042.JPG", "Canon EOS R6", 7, "2024/05/01/IMG_0042.jpg"},
+		{"{model}/{seq:0000}.{ext}", "DSC_0001.NEF", " Nikon Z 6 ", 12, "Nikon_Z_6/0012.nef"},
+		{"{date}_{seq}_{name}.{ext}", "a.CR3", "", 3, "2024-05-01_3_a.cr3"},
+		{"{seq:00}", "IMG.JPG", "", 1234, "1234"},
+		{"{date:15h04}/{name}", "noext", "", 1, "10h20/noext"},
+		{"{unknown}/{name}.{ext}", "IMG.JPG", "", 1, "{unknown}/IMG.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if got := renderTemplate(tt.template, tt.name, tt.model, date, tt.sequence); got != tt.want {
+				t.Errorf("renderTemplate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupSuffix(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		existing []string
+		dests    []string
+		want     string
+	}{
+		{"free", nil, []string{"a/IMG.jpg", "a/IMG.cr3"}, ""},
+		{"JPEG taken", []string{"a/IMG.jpg"}, []string{"a/IMG.jpg", "a/IMG.cr3"}, "_1"},
+		{"RAW taken", []string{"a/IMG.cr3"}, []string{"a/IMG.jpg", "a/IMG.cr3"}, "_1"},
+		{"both suffixes taken", []string{"a/IMG.jpg", "a/IMG_1.cr3"}, []string{"a/IMG.jpg", "a/IMG.cr3"}, "_2"},
+		{"other name taken", []string{"a/OTHER.jpg"}, []string{"a/IMG.jpg"}, ""},
+		{"no extension", []string{"a/IMG"}, []string{"a/IMG"}, "_1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				local := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(local, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := groupSuffix(dir, tt.dests); got != tt.want {
+				t.Errorf("groupSuffix = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSuffix(t *testing.T) {
+
+	tests := []struct {
+		dest   string
+		suffix string
+		want   string
+	}{
+		{"2024/05/01/IMG.jpg", "_1", "2024/05/01/IMG_1.jpg"},
+		{"2024.05/IMG", "_2", "2024.05/IMG_2"},
+		{"IMG.tar.gz", "_1", "IMG.tar_1.gz"},
+		{"IMG.jpg", "", "IMG.jpg"},
+	}
+
+	for _, tt := range tests {
+		if got := withSuffix(tt.dest, tt.suffix); got != tt.want {
+			t.Errorf("withSuffix(%q, %q) = %q, want %q", tt.dest, tt.suffix, got, tt.want)
+		}
+	}
+}
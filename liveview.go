@@ -0,0 +1,125 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// widget which raises the mirror and opens the live view on Canon and Nikon bodies
+const viewfinderWidget = "viewfinder"
+
+// LiveViewOptions
+type LiveViewOptions struct {
+	MaxFPS float64 // upper limit of captured frames per second, 0 captures as fast as the camera allows
+}
+
+// Frame is a single live view frame
+type Frame struct {
+	Data     []byte    // JPEG image
+	Time     time.Time // capture time
+	Sequence uint64    // number of the frame, gaps mean frames dropped for a slow consumer
+	Err      error     // set on the last frame when the camera failed, Data is empty then
+}
+
+// LiveView streams preview frames until ctx is done or the camera fails, then leaves live view and closes the channel
+// frames are dropped instead of blocking the capture loop when the consumer is slow, the failing frame never is
+// other calls on the camera stay possible, they run between two frames
+func (c *Camera) LiveView(ctx context.Context, options LiveViewOptions) (<-chan Frame, error) {
+
+	err := c.startLiveView()
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	var interval time.Duration
+	if options.MaxFPS > 0 {
+		interval = time.Duration(float64(time.Second) / options.MaxFPS)
+	}
+
+	frames := make(chan Frame, 1)
+
+	go func() {
+		defer close(frames)
+		defer c.stopLiveView()
+
+		var sequence uint64
+		for {
+			start := time.Now()
+
+			var buffer bytes.Buffer
+			err := c.CapturePreview(&buffer)
+			if err != nil {
+				Log.Error(err.Error())
+				// the loop is the only sender, the buffer is free once the stale frame is gone
+				select {
+				case <-frames:
+				default:
+				}
+				frames <- Frame{Time: start, Sequence: sequence, Err: err}
+				return
+			}
+
+			frame := Frame{
+				Data:     buffer.Bytes(),
+				Time:     start,
+				Sequence: sequence,
+			}
+			sequence++
+
+			select {
+			case frames <- frame:
+			default:
+				// replace the stale frame nobody picked up yet
+				select {
+				case <-frames:
+				default:
+				}
+				select {
+				case frames <- frame:
+				default:
+				}
+			}
+
+			wait := interval - time.Since(start)
+			if wait < 0 {
+				wait = 0
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+
+	return frames, nil
+}
+
+// startLiveView
+func (c *Camera) startLiveView() error {
+
+	if _, err := c.getGpWidgetByName(viewfinderWidget); err != nil {
+		// drivers without a viewfinder widget enter live view on the first preview
+		return c.CapturePreview(io.Discard)
+	}
+
+	name, value := viewfinderWidget, "1"
+	return c.setValue(&name, &value)
+}
+
+// stopLiveView
+func (c *Camera) stopLiveView() error {
+
+	if _, err := c.getGpWidgetByName(viewfinderWidget); err != nil {
+		return nil
+	}
+
+	name, value := viewfinderWidget, "0"
+	return c.setValue(&name, &value)
+}
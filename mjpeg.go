@@ -0,0 +1,234 @@
+package gogp2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// boundary of the multipart live view stream
+const mjpegBoundary = "gogp2frame"
+
+// LiveViewServer serves the live view of one camera to any number of viewers
+// GET <prefix>/stream is a multipart/x-mixed-replace MJPEG stream, GET <prefix>/snapshot a single JPEG frame
+type LiveViewServer struct {
+	camera  *Camera
+	options LiveViewOptions
+
+	mu       sync.Mutex
+	viewers  map[chan Frame]struct{}
+	last     *Frame
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopping chan struct{} // done of a capture loop still shutting down
+}
+
+// NewLiveViewServer
+func (c *Camera) NewLiveViewServer(options LiveViewOptions) *LiveViewServer {
+	return &LiveViewServer{
+		camera:  c,
+		options: options,
+		viewers: map[chan Frame]struct{}{},
+	}
+}
+
+// ServeHTTP
+func (s *LiveViewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/snapshot"):
+		s.serveSnapshot(w, r)
+	default:
+		s.serveStream(w, r)
+	}
+}
+
+// Close stops the capture loop and disconnects all viewers
+func (s *LiveViewServer) Close() {
+
+	s.mu.Lock()
+	cancel, done, stopping := s.cancel, s.done, s.stopping
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	if stopping != nil {
+		<-stopping
+	}
+}
+
+// serveStream
+func (s *LiveViewServer) serveStream(w http.ResponseWriter, r *http.Request) {
+
+	viewer, err := s.subscribe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer s.unsubscribe(viewer)
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame, ok := <-viewer:
+			if !ok || frame.Err != nil {
+				return
+			}
+			_, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", mjpegBoundary, len(frame.Data))
+			if err == nil {
+				_, err = w.Write(frame.Data)
+			}
+			if err == nil {
+				_, err = w.Write([]byte("\r\n"))
+			}
+			if err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// serveSnapshot
+func (s *LiveViewServer) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+
+	viewer, err := s.subscribe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer s.unsubscribe(viewer)
+
+	var frame Frame
+	var ok bool
+	select {
+	case <-r.Context().Done():
+		return
+	case frame, ok = <-viewer:
+	}
+	if !ok {
+		http.Error(w, "live view stopped", http.StatusServiceUnavailable)
+		return
+	}
+	if frame.Err != nil {
+		http.Error(w, frame.Err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Last-Modified", frame.Time.UTC().Format(http.TimeFormat))
+	w.Write(frame.Data)
+}
+
+// subscribe starts the capture loop for the first viewer
+func (s *LiveViewServer) subscribe() (chan Frame, error) {
+
+	s.mu.Lock()
+	// the camera is free again only once the previous capture loop left live view
+	for s.cancel == nil && s.stopping != nil {
+		stopping := s.stopping
+		s.mu.Unlock()
+		<-stopping
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+
+	viewer := make(chan Frame, 1)
+
+	if s.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		frames, err := s.camera.LiveView(ctx, s.options)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		s.cancel = cancel
+		s.done = make(chan struct{})
+		go s.fanOut(frames, s.done)
+	} else if s.last != nil && time.Since(s.last.Time) < time.Second {
+		// new viewers start with the latest frame instead of waiting for the next one
+		viewer <- *s.last
+	}
+
+	s.viewers[viewer] = struct{}{}
+	return viewer, nil
+}
+
+// unsubscribe stops the capture loop after the last viewer left
+func (s *LiveViewServer) unsubscribe(viewer chan Frame) {
+
+	s.mu.Lock()
+	if _, ok := s.viewers[viewer]; ok {
+		delete(s.viewers, viewer)
+		close(viewer)
+	}
+	cancel, done := s.cancel, s.done
+	if len(s.viewers) > 0 || cancel == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel = nil
+	s.done = nil
+	s.last = nil
+	s.stopping = done
+	s.mu.Unlock()
+
+	cancel()
+	<-done
+}
+
+// fanOut copies every frame to all viewers, slow viewers lose frames
+// a camera error reaches the viewers as the last frame before they are disconnected
+func (s *LiveViewServer) fanOut(frames <-chan Frame, done chan struct{}) {
+
+	for frame := range frames {
+		s.mu.Lock()
+		if s.done == done {
+			if frame.Err == nil {
+				last := frame
+				s.last = &last
+			}
+			for viewer := range s.viewers {
+				select {
+				case <-viewer:
+				default:
+				}
+				viewer <- frame
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if s.done == done {
+		// the loop ended by itself, the viewers are disconnected
+		for viewer := range s.viewers {
+			delete(s.viewers, viewer)
+			close(viewer)
+		}
+		s.cancel = nil
+		s.done = nil
+		s.last = nil
+	}
+	if s.stopping == done {
+		s.stopping = nil
+	}
+	s.mu.Unlock()
+
+	Log.Trace("live view server stopped")
+	close(done)
+}
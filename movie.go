@@ -0,0 +1,77 @@
+package gogp2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// movie widgets shared by the Canon and Nikon drivers
+const (
+	movieWidget       = "movie"
+	movieTargetWidget = "movierecordtarget"
+)
+
+// time the camera may take to write the clip after the recording stopped
+const movieFileTimeout = 60 * time.Second
+
+// StartMovie starts the video recording
+// drivers without a "movie" widget, like those offering only GP_CAPTURE_MOVIE, return ErrNotSupported
+func (c *Camera) StartMovie() error {
+
+	if _, err := c.getGpWidgetByName(movieWidget); err != nil {
+		return ErrNotSupported
+	}
+
+	if target, err := c.getWidgetByName(movieTargetWidget); err == nil && target.Value == "None" {
+		// Canon records to nowhere unless a target is chosen
+		err := c.SetWigetValueByName(movieTargetWidget, "Card")
+		if err != nil {
+			return err
+		}
+	}
+
+	name, value := movieWidget, "1"
+	err := c.setValue(&name, &value)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	Log.Info("movie recording started")
+	return nil
+}
+
+// StopMovie stops the video recording and returns the path of the clip on the camera
+// the camera mutex is released between short event waits, other calls still run while the clip is written
+func (c *Camera) StopMovie() (*CameraFilePath, error) {
+
+	if _, err := c.getGpWidgetByName(movieWidget); err != nil {
+		return nil, ErrNotSupported
+	}
+
+	name, value := movieWidget, "0"
+	err := c.setValue(&name, &value)
+	if err != nil {
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	path, err := c.waitForFile(context.Background(), movieFileTimeout)
+	if err != nil {
+		err := fmt.Sprintf("movie recording stopped but no clip was written: %s", err.Error())
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	Log.Info("movie recording stopped: " + path.Folder + "/" + path.Name)
+	return path, nil
+}
+
+// DownloadMovie streams the clip to buffer in chunks
+func (c *Camera) DownloadMovie(ctx context.Context, buffer io.Writer, path *CameraFilePath) error {
+	return c.DownloadFile(ctx, *path, FileTypeNormal, buffer)
+}
@@ -0,0 +1,168 @@
+package gogp2
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// SunEvent is a position of the sun during the day
+type SunEvent string
+
+// sun events
+const (
+	AstronomicalDawn SunEvent = "astronomicalDawn"
+	NauticalDawn     SunEvent = "nauticalDawn"
+	CivilDawn        SunEvent = "civilDawn"
+	Sunrise          SunEvent = "sunrise"
+	SolarNoon        SunEvent = "solarNoon"
+	Sunset           SunEvent = "sunset"
+	CivilDusk        SunEvent = "civilDusk"
+	NauticalDusk     SunEvent = "nauticalDusk"
+	AstronomicalDusk SunEvent = "astronomicalDusk"
+)
+
+// altitude of the sun center in degrees and whether the sun is rising
+var sunEvents = map[SunEvent]struct {
+	altitude float64
+	rising   bool
+}{
+	AstronomicalDawn: {-18, true},
+	NauticalDawn:     {-12, true},
+	CivilDawn:        {-6, true},
+	Sunrise:          {-0.833, true},
+	Sunset:           {-0.833, false},
+	CivilDusk:        {-6, false},
+	NauticalDusk:     {-12, false},
+	AstronomicalDusk: {-18, false},
+}
+
+// SunSchedule is a time relative to a sun event at a place, like 30 minutes before civil dusk
+type SunSchedule struct {
+	Latitude  float64 // degrees, north positive
+	Longitude float64 // degrees, east positive
+	Event     SunEvent
+	Offset    time.Duration // negative is before the event
+}
+
+// SunTime returns the time of event on the day of date, false if the sun does not reach it that day
+func SunTime(event SunEvent, date time.Time, latitude float64, longitude float64) (time.Time, bool) {
+
+	y, m, d := date.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	jd := float64(midnight.Unix())/86400 + 2440587.5
+
+	// sunrise equation, about a minute of accuracy
+	n := math.Ceil(jd - 2451545.0 + 0.0008)
+	jStar := n - longitude/360
+	M := math.Mod(357.5291+0.98560028*jStar, 360)
+	C := 1.9148*sin(M) + 0.02*sin(2*M) + 0.0003*sin(3*M)
+	lambda := math.Mod(M+C+180+102.9372, 360)
+	transit := 2451545.0 + jStar + 0.0053*sin(M) - 0.0069*sin(2*lambda)
+
+	if event == SolarNoon {
+		return julianToTime(transit, date.Location()), true
+	}
+
+	e, ok := sunEvents[event]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	sinDecl := sin(lambda) * sin(23.4397)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHour := (sin(e.altitude) - sin(latitude)*sinDecl) / (math.Cos(latitude*math.Pi/180) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, false
+	}
+	hour := math.Acos(cosHour) * 180 / math.Pi
+
+	if e.rising {
+		return julianToTime(transit-hour/360, date.Location()), true
+	}
+	return julianToTime(transit+hour/360, date.Location()), true
+}
+
+// Next returns the first time of the schedule after after
+func (s SunSchedule) Next(after time.Time) (time.Time, error) {
+
+	for day := -1; day <= 366; day++ {
+		t, ok := SunTime(s.Event, after.AddDate(0, 0, day), s.Latitude, s.Longitude)
+		if !ok {
+			continue
+		}
+		t = t.Add(s.Offset)
+		if t.After(after) {
+			return t, nil
+		}
+	}
+
+	err := fmt.Sprintf("sun never reaches %s at %f, %f", s.Event, s.Latitude, s.Longitude)
+	Log.Error(err)
+	return time.Time{}, fmt.Errorf(err)
+}
+
+// ScheduleSun calls fn at every time of the schedule until ctx is done or fn returns an error
+func ScheduleSun(ctx context.Context, schedule SunSchedule, fn func(ctx context.Context, at time.Time) error) error {
+
+	for {
+		at, err := schedule.Next(time.Now())
+		if err != nil {
+			return err
+		}
+
+		Log.Info(fmt.Sprintf("next %s at %s", schedule.Event, at.Format(time.RFC3339)))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(at)):
+		}
+
+		err = fn(ctx, at)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SunTimelapse runs a timelapse from the next start until the following stop of the schedules
+func (c *Camera) SunTimelapse(ctx context.Context, start SunSchedule, stop SunSchedule, options TimelapseOptions, consumer func(TimelapseFrame) error) error {
+
+	startAt, err := start.Next(time.Now())
+	if err != nil {
+		return err
+	}
+
+	stopAt, err := stop.Next(startAt)
+	if err != nil {
+		return err
+	}
+
+	Log.Info(fmt.Sprintf("timelapse from %s to %s", startAt.Format(time.RFC3339), stopAt.Format(time.RFC3339)))
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(time.Until(startAt)):
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, stopAt)
+	defer cancel()
+
+	options.Frames = 0
+	return c.Timelapse(ctx, options, consumer)
+}
+
+// sin of degrees
+func sin(degrees float64) float64 {
+	return math.Sin(degrees * math.Pi / 180)
+}
+
+// julianToTime
+func julianToTime(jd float64, loc *time.Location) time.Time {
+	return time.Unix(0, int64((jd-2440587.5)*86400*float64(time.Second))).In(loc)
+}
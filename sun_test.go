@@ -0,0 +1,84 @@
+package gogp2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTime(t *testing.T) {
+
+	berlin := time.FixedZone("CEST", 2*3600)
+	sydney := time.FixedZone("AEDT", 11*3600)
+
+	tests := []struct {
+		name      string
+		event     SunEvent
+		date      time.Time
+		latitude  float64
+		longitude float64
+		want      time.Time
+		ok        bool
+	}{
+		{"Berlin sunrise", Sunrise, time.Date(2024, 6, 21, 12, 0, 0, 0, berlin), 52.52, 13.405, time.Date(2024, 6, 21, 4, 43, 0, 0, berlin), true},
+		{"Berlin solar noon", SolarNoon, time.Date(2024, 6, 21, 12, 0, 0, 0, berlin), 52.52, 13.405, time.Date(2024, 6, 21, 13, 8, 0, 0, berlin), true},
+		{"Berlin sunset", Sunset, time.Date(2024, 6, 21, 12, 0, 0, 0, berlin), 52.52, 13.405, time.Date(2024, 6, 21, 21, 33, 0, 0, berlin), true},
+		{"Berlin civil dusk", CivilDusk, time.Date(2024, 6, 21, 12, 0, 0, 0, berlin), 52.52, 13.405, time.Date(2024, 6, 21, 22, 22, 0, 0, berlin), true},
+		{"Berlin astronomical dusk at midsummer", AstronomicalDusk, time.Date(2024, 6, 21, 12, 0, 0, 0, berlin), 52.52, 13.405, time.Time{}, false},
+		{"equinox sunrise", Sunrise, time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC), 0, 0, time.Date(2024, 3, 20, 6, 4, 0, 0, time.UTC), true},
+		{"equinox sunset", Sunset, time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC), 0, 0, time.Date(2024, 3, 20, 18, 11, 0, 0, time.UTC), true},
+		{"Sydney sunrise", Sunrise, time.Date(2024, 12, 21, 12, 0, 0, 0, sydney), -33.87, 151.21, time.Date(2024, 12, 21, 5, 41, 0, 0, sydney), true},
+		{"Sydney sunset", Sunset, time.Date(2024, 12, 21, 12, 0, 0, 0, sydney), -33.87, 151.21, time.Date(2024, 12, 21, 20, 5, 0, 0, sydney), true},
+		{"Tromso midnight sun", Sunset, time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC), 69.65, 18.96, time.Time{}, false},
+		{"Tromso polar night", Sunrise, time.Date(2024, 12, 21, 12, 0, 0, 0, time.UTC), 69.65, 18.96, time.Time{}, false},
+		{"unknown event", SunEvent("moonrise"), time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC), 0, 0, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, ok := SunTime(tt.event, tt.date, tt.latitude, tt.longitude)
+			if ok != tt.ok {
+				t.Fatalf("SunTime = %v, %v, want ok %v", got, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+
+			// the sunrise equation is accurate to about a minute, the reference times are rounded
+			if diff := got.Sub(tt.want); diff < -3*time.Minute || diff > 3*time.Minute {
+				t.Errorf("SunTime = %s, want %s", got.Format(time.RFC3339), tt.want.Format(time.RFC3339))
+			}
+			if got.Location() != tt.date.Location() {
+				t.Errorf("SunTime location = %s, want %s", got.Location(), tt.date.Location())
+			}
+		})
+	}
+}
+
+func TestSunScheduleNext(t *testing.T) {
+
+	berlin := time.FixedZone("CEST", 2*3600)
+	schedule := SunSchedule{Latitude: 52.52, Longitude: 13.405, Event: Sunset, Offset: -30 * time.Minute}
+
+	tests := []struct {
+		name  string
+		after time.Time
+		want  time.Time
+	}{
+		{"same day", time.Date(2024, 6, 21, 12, 0, 0, 0, berlin), time.Date(2024, 6, 21, 21, 3, 0, 0, berlin)},
+		{"after the offset time", time.Date(2024, 6, 21, 21, 10, 0, 0, berlin), time.Date(2024, 6, 22, 21, 3, 0, 0, berlin)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := schedule.Next(tt.after)
+			if err != nil {
+				t.Fatalf("Next: %v", err)
+			}
+			if diff := got.Sub(tt.want); diff < -3*time.Minute || diff > 3*time.Minute {
+				t.Errorf("Next = %s, want %s", got.Format(time.RFC3339), tt.want.Format(time.RFC3339))
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package gogp2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// default name of the sync state file, kept in the destination folder
+const syncStateFile = ".gogp2-sync.json"
+
+// SyncOptions
+type SyncOptions struct {
+	Root      string                                         // camera folder to mirror, "/" by default
+	Dest      string                                         // local folder, the camera folder structure is kept below it
+	Delete    bool                                           // delete files from the camera after a download verified by size
+	Filter    func(file CameraFilePath, info *FileInfo) bool // files for which Filter returns false are ignored, nil syncs all
+	StateFile string                                         // state of the last sync, Dest/.gogp2-sync.json by default
+	Progress  func(SyncProgress)                             // called after each file
+}
+
+// SyncProgress
+type SyncProgress struct {
+	File    CameraFilePath
+	Done    int   // files handled so far
+	Total   int   // files to handle
+	Bytes   int64 // bytes downloaded so far
+	Skipped bool  // the file was already up to date
+}
+
+// SyncResult
+type SyncResult struct {
+	Downloaded int   `json:"downloaded"`
+	Skipped    int   `json:"skipped"`
+	Deleted    int   `json:"deleted"`
+	Unverified int   `json:"unverified"` // files left on the camera because the download could not be verified
+	Bytes      int64 `json:"bytes"`
+}
+
+// syncEntry is the state of one synced file
+type syncEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Local   string    `json:"local,omitempty"` // path below Dest, the camera path if empty
+}
+
+// Sync downloads the files of the camera which are new or changed since the last sync
+// a local file is never replaced, a file the camera reused the name of is downloaded under a new name
+func (c *Camera) Sync(ctx context.Context, options SyncOptions) (*SyncResult, error) {
+
+	if options.Dest == "" {
+		err := "sync destination is empty"
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+	if options.Root == "" {
+		options.Root = "/"
+	}
+	if options.StateFile == "" {
+		options.StateFile = filepath.Join(options.Dest, syncStateFile)
+	}
+
+	state, err := loadSyncState(options.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	type syncFile struct {
+		path CameraFilePath
+		info *FileInfo
+	}
+	files := []syncFile{}
+
+	err = c.Walk(ctx, options.Root, func(file CameraFilePath, err error) error {
+		if err != nil {
+			return err
+		}
+		if file.Isdir {
+			return nil
+		}
+		info, err := c.FileInfo(file)
+		if err != nil {
+			return err
+		}
+		if options.Filter != nil && !options.Filter(file, info) {
+			return nil
+		}
+		files = append(files, syncFile{path: file, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{}
+
+	for i, file := range files {
+
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		key := joinPath(file.path)
+		entry := syncEntry{Size: file.info.File.Size, ModTime: file.info.File.ModTime}
+
+		skipped := false
+		if old, ok := state[key]; ok && old.Size == entry.Size && old.ModTime.Equal(entry.ModTime) {
+			local := old.Local
+			if local == "" {
+				local = key
+			}
+			if stat, err := os.Stat(filepath.Join(options.Dest, filepath.FromSlash(local))); err == nil && stat.Size() == entry.Size {
+				skipped = true
+			}
+		}
+
+		if skipped {
+			result.Skipped++
+		} else {
+			local, written, err := c.downloadAtomic(ctx, file.path, filepath.Join(options.Dest, filepath.FromSlash(key)), entry.Size)
+			if err != nil {
+				return result, err
+			}
+			result.Downloaded++
+			result.Bytes += written
+
+			if entry.Local, err = filepath.Rel(options.Dest, local); err != nil {
+				Log.Error(err.Error())
+				return result, err
+			}
+			entry.Local = filepath.ToSlash(entry.Local)
+			state[key] = entry
+			err = saveSyncState(options.StateFile, state)
+			if err != nil {
+				return result, err
+			}
+		}
+
+		if options.Delete && entry.Size <= 0 {
+			// without the size from the camera the download cannot be verified
+			Log.Warning(fmt.Sprintf("%s not deleted, the camera does not report its size", key))
+			result.Unverified++
+		} else if options.Delete {
+			err := c.DeleteFile(&file.path)
+			if err != nil {
+				return result, err
+			}
+			result.Deleted++
+		}
+
+		if options.Progress != nil {
+			options.Progress(SyncProgress{
+				File:    file.path,
+				Done:    i + 1,
+				Total:   len(files),
+				Bytes:   result.Bytes,
+				Skipped: skipped,
+			})
+		}
+	}
+
+	Log.Info(fmt.Sprintf("sync done: %d downloaded, %d skipped, %d deleted", result.Downloaded, result.Skipped, result.Deleted))
+	return result, nil
+}
+
+// downloadAtomic downloads file to a temporary file next to local and renames it once complete
+// size is the expected size, 0 if unknown, an existing local file is kept and the download gets a free name
+func (c *Camera) downloadAtomic(ctx context.Context, file CameraFilePath, local string, size int64) (string, int64, error) {
+
+	err := os.MkdirAll(filepath.Dir(local), 0755)
+	if err != nil {
+		Log.Error(err.Error())
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(local), "."+filepath.Base(local)+".*.part")
+	if err != nil {
+		Log.Error(err.Error())
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := c.DownloadFileFrom(ctx, file, FileTypeNormal, 0, tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		Log.Error(err.Error())
+		return "", written, err
+	}
+
+	if size > 0 && written != size {
+		err := fmt.Sprintf("download of %s incomplete: %d of %d bytes", joinPath(file), written, size)
+		Log.Error(err)
+		return "", written, fmt.Errorf(err)
+	}
+
+	local = freeLocalName(local)
+	err = os.Rename(tmp.Name(), local)
+	if err != nil {
+		Log.Error(err.Error())
+		return "", written, err
+	}
+
+	return local, written, nil
+}
+
+// freeLocalName appends a counter to local while a file of that name exists
+func freeLocalName(local string) string {
+
+	ext := filepath.Ext(local)
+	base := strings.TrimSuffix(local, ext)
+
+	name := local
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = base + "_" + strconv.Itoa(i) + ext
+	}
+}
+
+// loadSyncState
+func loadSyncState(name string) (map[string]syncEntry, error) {
+
+	state := map[string]syncEntry{}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		Log.Error(err.Error())
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		err := fmt.Sprintf("invalid sync state file %s: %s", name, err.Error())
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+
+	return state, nil
+}
+
+// saveSyncState writes the state to a temporary file and renames it, so it is never half written
+func saveSyncState(name string, state map[string]syncEntry) error {
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	tmp := name + ".part"
+	err = os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package gogp2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFreeLocalName(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		existing []string
+		want     string
+	}{
+		{"IMG_0001.JPG", nil, "IMG_0001.JPG"},
+		{"IMG_0001.JPG", []string{"IMG_0001.JPG"}, "IMG_0001_1.JPG"},
+		{"IMG_0001.JPG", []string{"IMG_0001.JPG", "IMG_0001_1.JPG"}, "IMG_0001_2.JPG"},
+		{"MOVIE", []string{"MOVIE"}, "MOVIE_1"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, name := range tt.existing {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("earlier photo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := freeLocalName(filepath.Join(dir, tt.name)); got != filepath.Join(dir, tt.want) {
+			t.Errorf("freeLocalName(%s) with %v = %s, want %s", tt.name, tt.existing, filepath.Base(got), tt.want)
+		}
+	}
+}
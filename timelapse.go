@@ -0,0 +1,203 @@
+package gogp2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	Log "github.com/qazf88/golog"
+)
+
+// TimelapseOptions
+type TimelapseOptions struct {
+	Interval time.Duration // time between the start of two frames
+	Frames   int           // number of frames, 0 runs until ctx is done
+	Ramp     *RampOptions  // exposure ramping, nil keeps the exposure fixed
+}
+
+// RampOptions controls the exposure ramping between timelapse frames
+type RampOptions struct {
+	Target     float64             // mean luma to hold, 118 by default
+	Parameters []ExposureParameter // parameters adjusted in order, shutter speed, ISO then aperture by default
+	Region     image.Rectangle     // measured region of the live view frame in pixels, empty measures the whole frame
+	Smoothing  float64             // weight of the last frame in the brightness average, 0..1, 0.3 by default
+	MaxStep    float64             // stops changed at most between two frames, 1/3 by default
+	Deadband   float64             // stops of error ignored to avoid flicker, 1/6 by default
+}
+
+// TimelapseFrame
+type TimelapseFrame struct {
+	Sequence   int
+	Time       time.Time
+	Data       []byte
+	Brightness float64           // mean luma of the frame, 0 without ramping
+	Values     map[string]string // exposure widget values changed after the frame
+}
+
+// Timelapse captures a frame every options.Interval and passes it to consumer
+func (c *Camera) Timelapse(ctx context.Context, options TimelapseOptions, consumer func(TimelapseFrame) error) error {
+
+	if options.Interval <= 0 {
+		err := fmt.Sprintf("invalid timelapse interval: %s", options.Interval)
+		Log.Error(err)
+		return fmt.Errorf(err)
+	}
+
+	var ramp *ramper
+	if options.Ramp != nil {
+		ramp = newRamper(*options.Ramp, options.Interval)
+	}
+
+	next := time.Now()
+	for sequence := 0; options.Frames <= 0 || sequence < options.Frames; sequence++ {
+
+		select {
+		case <-ctx.Done():
+			if options.Frames <= 0 {
+				return nil
+			}
+			return ctx.Err()
+		case <-time.After(time.Until(next)):
+		}
+		next = next.Add(options.Interval)
+
+		frame := TimelapseFrame{Sequence: sequence, Time: time.Now()}
+
+		var buffer bytes.Buffer
+		err := c.CapturePhoto(&buffer)
+		if err != nil {
+			return err
+		}
+		frame.Data = buffer.Bytes()
+
+		if ramp != nil {
+			frame.Brightness, frame.Values, err = ramp.step(c, time.Since(frame.Time))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = consumer(frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ramper smooths the measured brightness and steps the exposure towards the target
+type ramper struct {
+	options  RampOptions
+	interval time.Duration
+	smoothed float64
+}
+
+// newRamper
+func newRamper(options RampOptions, interval time.Duration) *ramper {
+
+	if options.Target <= 0 {
+		options.Target = 118
+	}
+	if len(options.Parameters) == 0 {
+		options.Parameters = []ExposureParameter{ExposureShutter, ExposureISO, ExposureAperture}
+	}
+	if options.Smoothing <= 0 || options.Smoothing > 1 {
+		options.Smoothing = 0.3
+	}
+	if options.MaxStep <= 0 {
+		options.MaxStep = 1.0 / 3
+	}
+	if options.Deadband <= 0 {
+		options.Deadband = 1.0 / 6
+	}
+
+	return &ramper{options: options, interval: interval, smoothed: -1}
+}
+
+// step meters the scene and adjusts the exposure for the next frame
+// captureTime is the time the last capture took with exposure and download
+func (r *ramper) step(c *Camera, captureTime time.Duration) (float64, map[string]string, error) {
+
+	// decoding the full resolution frame takes seconds on small hosts, the live view frame is enough to meter
+	img, err := c.CapturePreviewImage()
+	if err != nil {
+		Log.Warning("exposure ramping skipped: " + err.Error())
+		return 0, nil, nil
+	}
+
+	brightness := measureBrightness(img, r.options.Region)
+	if r.smoothed < 0 {
+		r.smoothed = brightness
+	} else {
+		r.smoothed += r.options.Smoothing * (brightness - r.smoothed)
+	}
+
+	stops := brightnessStops(r.smoothed, r.options.Target)
+	if math.Abs(stops) < r.options.Deadband {
+		return brightness, nil, nil
+	}
+	stops = math.Max(-r.options.MaxStep, math.Min(r.options.MaxStep, stops))
+
+	values := map[string]string{}
+	applied, err := c.shiftExposure(r.options.Parameters, stops, values, r.maxShutter(c, captureTime))
+	if err != nil {
+		return brightness, values, err
+	}
+
+	// the average follows the new exposure so the change is not applied twice
+	r.smoothed *= math.Pow(2, applied/previewGamma)
+
+	return brightness, values, nil
+}
+
+// maxShutter is the longest shutter speed which keeps the schedule, the part of captureTime
+// not spent exposing is taken as download time of the next frame
+func (r *ramper) maxShutter(c *Camera, captureTime time.Duration) time.Duration {
+
+	overhead := captureTime
+	for _, param := range r.options.Parameters {
+		if param != ExposureShutter {
+			continue
+		}
+		wName, err := c.exposureWidgetName(ExposureShutter)
+		if err != nil {
+			break
+		}
+		current, err := c.GetWidgetValueByName(wName)
+		if err != nil {
+			break
+		}
+		if ev, ok := exposureValue(ExposureShutter, current); ok {
+			overhead -= time.Duration(math.Pow(2, ev) * float64(time.Second))
+		}
+	}
+	if overhead < 0 {
+		overhead = 0
+	}
+
+	max := r.interval - overhead
+	if max <= 0 {
+		// the interval is too short already, no shutter speed fits
+		max = time.Nanosecond
+	}
+
+	return max
+}
+
+// shutterChoicesUpTo returns the shutter speeds of choices not longer than max
+func shutterChoicesUpTo(choices []string, max time.Duration) []string {
+
+	limited := []string{}
+	for _, choice := range choices {
+		ev, ok := exposureValue(ExposureShutter, choice)
+		if ok && math.Pow(2, ev) <= max.Seconds() {
+			limited = append(limited, choice)
+		}
+	}
+
+	return limited
+}
@@ -0,0 +1,29 @@
+package gogp2
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShutterChoicesUpTo(t *testing.T) {
+
+	choices := []string{"30", "15", "2", "1", "0.5", "1/4", "1/125", "bulb", "Auto"}
+
+	tests := []struct {
+		max  time.Duration
+		want []string
+	}{
+		{time.Minute, []string{"30", "15", "2", "1", "0.5", "1/4", "1/125"}},
+		{10 * time.Second, []string{"2", "1", "0.5", "1/4", "1/125"}},
+		{time.Second, []string{"1", "0.5", "1/4", "1/125"}},
+		{100 * time.Millisecond, []string{"1/125"}},
+		{time.Nanosecond, []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := shutterChoicesUpTo(choices, tt.max); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shutterChoicesUpTo(%s) = %v, want %v", tt.max, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,10 @@ package gogp2
 // #include <gphoto2/gphoto2.h>
 // #include <string.h>
 import "C"
+import (
+	"sync"
+	"time"
+)
 
 type GoContext *C.GPContext
 type CameraWidget struct {
@@ -16,6 +20,9 @@ type Camera struct {
 	Camera     *C.Camera
 	Context    *C.GPContext
 	RootWidget *C.CameraWidget
+	middleware []CaptureMiddleware
+	hooksMu    sync.Mutex // guards middleware, separate from mu as middleware calls the camera
+	mu         sync.Mutex // serialises the calls into libgphoto2
 }
 
 type widget struct {
@@ -48,6 +55,25 @@ type CameraFilePath struct {
 	Children []CameraFilePath
 }
 
+// FileInfo is the information the camera has about a file
+type FileInfo struct {
+	File    FilePartInfo `json:"file"`
+	Preview FilePartInfo `json:"preview"`
+	Audio   FilePartInfo `json:"audio"`
+}
+
+// FilePartInfo fields the camera does not report are left empty
+type FilePartInfo struct {
+	Size       int64     `json:"size"`
+	Type       string    `json:"type"` // MIME type
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	Readable   bool      `json:"readable"`
+	Deletable  bool      `json:"deletable"`
+	Downloaded bool      `json:"downloaded"`
+	ModTime    time.Time `json:"modTime"`
+}
+
 const (
 	OK = 0
 )
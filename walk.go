@@ -0,0 +1,154 @@
+package gogp2
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	Log "github.com/qazf88/golog"
+)
+
+// WalkFunc is called by Walk for every folder and file
+// err is set when the content of the folder could not be listed, the folder is then reported a second time
+// returning fs.SkipDir skips the folder, or the rest of the folder when called for a file
+type WalkFunc func(file CameraFilePath, err error) error
+
+// Walk visits root and everything below it in lexical order, like filepath.WalkDir
+func (c *Camera) Walk(ctx context.Context, root string, fn WalkFunc) error {
+
+	root = path.Clean("/" + root)
+	err := c.walk(ctx, folderPath(root), fn)
+	if err == fs.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+// Tree returns root with Children filled with its files and folders, recursively
+func (c *Camera) Tree(ctx context.Context, root string) (*CameraFilePath, error) {
+
+	node := folderPath(path.Clean("/" + root))
+	err := c.tree(ctx, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+// walk
+func (c *Camera) walk(ctx context.Context, dir CameraFilePath, fn WalkFunc) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := fn(dir, nil)
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.readDir(joinPath(dir))
+	if err != nil {
+		err = fn(dir, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.Isdir {
+			err = c.walk(ctx, entry, fn)
+		} else {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			err = fn(entry, nil)
+		}
+
+		if err == fs.SkipDir {
+			if entry.Isdir {
+				continue
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tree
+func (c *Camera) tree(ctx context.Context, node *CameraFilePath) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entries, err := c.readDir(joinPath(*node))
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		if entries[i].Isdir {
+			err := c.tree(ctx, &entries[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	node.Children = entries
+
+	return nil
+}
+
+// readDir lists the files and folders in folder sorted by name
+func (c *Camera) readDir(folder string) ([]CameraFilePath, error) {
+
+	entries := []CameraFilePath{}
+
+	folders, err := c.ListFolders(folder)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range folders {
+		entries = append(entries, CameraFilePath{Name: name, Folder: folder, Isdir: true})
+	}
+
+	files, res := c.ListFiles(folder)
+	if res != OK {
+		err := fmt.Sprintf("cannot list files in %s, error code: %d", folder, res)
+		Log.Error(err)
+		return nil, fmt.Errorf(err)
+	}
+	for _, name := range files {
+		entries = append(entries, CameraFilePath{Name: name, Folder: folder})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+// folderPath describes the folder at p
+func folderPath(p string) CameraFilePath {
+
+	if p == "/" {
+		return CameraFilePath{Name: "", Folder: "/", Isdir: true}
+	}
+
+	return CameraFilePath{Name: path.Base(p), Folder: path.Dir(p), Isdir: true}
+}
+
+// joinPath returns the full path of file
+func joinPath(file CameraFilePath) string {
+	return path.Join(file.Folder, file.Name)
+}
@@ -7,13 +7,17 @@ package gogp2
 import "C"
 import (
 	"encoding/json"
-
+	"errors"
 	"fmt"
+	"strconv"
 	"unsafe"
 
 	Log "github.com/qazf88/golog"
 )
 
+// errWidgetNotFound is wrapped in the error of lookups for a widget the camera does not have
+var errWidgetNotFound = errors.New("widget not found")
+
 // GetConfig
 func (c *Camera) GetConfig() (string, error) {
 
@@ -23,6 +27,9 @@ func (c *Camera) GetConfig() (string, error) {
 		return "", err
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	var arrayWidget []widget
 	var widgetSection, child *C.CameraWidget
 	defer C.free(unsafe.Pointer(widgetSection))
@@ -68,7 +75,10 @@ func (c *Camera) GetConfig() (string, error) {
 // GetWidgetChoicesByName
 func (c *Camera) GetWidgetChoicesByName(wName string) ([]string, error) {
 
-	childWidget, err := c.getGpWidgetByName(wName)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	childWidget, err := c.gpWidgetByName(wName)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +95,10 @@ func (c *Camera) GetWidgetChoicesByName(wName string) ([]string, error) {
 // GetWidgetByName
 func (c *Camera) GetWidgetByName(wName string) (string, error) {
 
-	childWidget, err := c.getGpWidgetByName(wName)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	childWidget, err := c.gpWidgetByName(wName)
 	if err != nil {
 		Log.Error(err.Error())
 		return "", err
@@ -107,7 +120,10 @@ func (c *Camera) GetWidgetByName(wName string) (string, error) {
 // GetWidgetValueByName
 func (c *Camera) GetWidgetValueByName(wName string) (string, error) {
 
-	childWidget, err := c.getGpWidgetByName(wName)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	childWidget, err := c.gpWidgetByName(wName)
 	if err != nil {
 		Log.Error(err.Error())
 		return "", err
@@ -330,7 +346,7 @@ restore:
 	return errors
 }
 
-// getRootWidget
+// getRootWidget, c.mu must be held
 func (c *Camera) getRootWidget() (**C.CameraWidget, error) {
 
 	var rootWidget *C.CameraWidget
@@ -360,7 +376,10 @@ func getStringWidgetName(_widget *C.CameraWidget) (string, error) {
 // getWidgetByName
 func (c *Camera) getWidgetByName(wName string) (widget, error) {
 
-	childWidget, err := c.getGpWidgetByName(wName)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	childWidget, err := c.gpWidgetByName(wName)
 	if err != nil {
 		return widget{}, err
 	}
@@ -460,7 +479,7 @@ func getWidgetValue(_widget *C.CameraWidget, _type C.CameraWidgetType) (string,
 	case typeWidgetButton:
 		return C.GoString(C_value), 0
 	case typeWidgetRange:
-		//return C.GoString(C_value), 0
+		return strconv.FormatFloat(float64(*(*C.float)(unsafe.Pointer(&C_value))), 'f', -1, 32), 0
 	case typeWidgetDate:
 		return fmt.Sprintf("%d", C_value), 0
 	case typeWidgetToggle:
@@ -510,10 +529,26 @@ func getWidgetType(_widget *C.CameraWidget) (C.CameraWidgetType, error) {
 // getGpWidgetByName
 func (c *Camera) getGpWidgetByName(wName string) (*C.CameraWidget, error) {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gpWidgetByName(wName)
+}
+
+// gpWidgetByName is getGpWidgetByName for callers already holding c.mu
+func (c *Camera) gpWidgetByName(wName string) (*C.CameraWidget, error) {
+
 	var childWidget *C.CameraWidget
 	defer C.free(unsafe.Pointer(childWidget))
 
+	if c.RootWidget == nil {
+		return nil, fmt.Errorf("could not retrieve widget by name '%s', camera configuration not loaded", wName)
+	}
+
 	res := C.gp_widget_get_child_by_name(c.RootWidget, C.CString(wName), (**C.CameraWidget)(unsafe.Pointer(&childWidget)))
+	if res == C.GP_ERROR_BAD_PARAMETERS {
+		return nil, fmt.Errorf("could not retrieve widget by name '%s': %w", wName, errWidgetNotFound)
+	}
 	if res != OK {
 		return nil, fmt.Errorf("could not retrieve widget by name '%s', error code: %d", wName, res)
 	}
@@ -522,26 +557,67 @@ func (c *Camera) getGpWidgetByName(wName string) (*C.CameraWidget, error) {
 
 // setValue
 func (c *Camera) setValue(wName *string, wValue *string) error {
+	return c.writeValue(*wName, *wValue, false)
+}
 
-	_widget, err := c.getGpWidgetByName(*wName)
+// forceValue sends the value even when the cached widget already holds it
+// needed for action widgets like autofocusdrive which the camera resets by itself
+func (c *Camera) forceValue(wName string, wValue string) error {
+	return c.writeValue(wName, wValue, true)
+}
+
+// writeValue
+func (c *Camera) writeValue(name string, value string, force bool) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_widget, err := c.gpWidgetByName(name)
 	if err != nil {
 		return err
 	}
 
-	C_value := C.CString(*wValue)
-	defer C.free(unsafe.Pointer(C_value))
+	wType, err := getWidgetType(_widget)
+	if err != nil {
+		return err
+	}
 
-	res := C.gp_widget_set_value(_widget, unsafe.Pointer(C_value))
+	var res C.int
+	switch wType {
+	case typeWidgetToggle, typeWidgetDate:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' for widget by name '%s'", value, name)
+		}
+		C_value := C.int(number)
+		res = C.gp_widget_set_value(_widget, unsafe.Pointer(&C_value))
+	case typeWidgetRange:
+		number, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' for widget by name '%s'", value, name)
+		}
+		C_value := C.float(number)
+		res = C.gp_widget_set_value(_widget, unsafe.Pointer(&C_value))
+	default:
+		C_value := C.CString(value)
+		defer C.free(unsafe.Pointer(C_value))
+		res = C.gp_widget_set_value(_widget, unsafe.Pointer(C_value))
+	}
 	if res != OK {
-		return fmt.Errorf("error setting the value for widget by name '%s', error code: %d", *wName, res)
+		return fmt.Errorf("error setting the value for widget by name '%s', error code: %d", name, res)
+	}
+
+	// libgphoto2 does not send a widget which value did not change
+	if force {
+		C.gp_widget_set_changed(_widget, 1)
 	}
 
-	C_name := C.CString(*wName)
+	C_name := C.CString(name)
 	defer C.free(unsafe.Pointer(C_name))
 
 	res = C.gp_camera_set_single_config(c.Camera, C_name, _widget, c.Context)
 	if res != OK {
-		return fmt.Errorf("error save widget by name '%s', error code: %d", *wName, res)
+		return fmt.Errorf("error save widget by name '%s', error code: %d", name, res)
 	}
 	return nil
 }